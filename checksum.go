@@ -0,0 +1,190 @@
+package database
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/infomodels/datadirectory"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VerifiedFile describes a data file whose checksum and size have been verified against the manifest.
+type VerifiedFile struct {
+	Table    string
+	FileName string // Relative to the data directory
+	Checksum string // Form "{algorithm}:{hex digest}", e.g. "sha256:7476...", or "" if the manifest has none
+	Size     int64  // Bytes
+}
+
+// Checksum algorithms, by name and by the length of their hex digests.
+var checksumHashes = map[string]func() hash.Hash{"md5": md5.New, "sha1": sha1.New, "sha256": sha256.New, "sha512": sha512.New}
+var checksumAlgorithmsByLength = map[int]string{32: "md5", 40: "sha1", 64: "sha256", 128: "sha512"}
+
+// parseChecksum splits a manifest checksum, either a bare hex digest or "{algorithm}:{hex digest}", into algorithm and digest.
+func parseChecksum(checksum string) (algorithm string, digest string, err error) {
+	digest = strings.ToLower(strings.TrimSpace(checksum))
+	if i := strings.Index(digest, ":"); i >= 0 {
+		algorithm, digest = digest[:i], digest[i+1:]
+	} else {
+		algorithm = checksumAlgorithmsByLength[len(digest)]
+	}
+	if _, ok := checksumHashes[algorithm]; !ok {
+		return "", "", fmt.Errorf("unrecognized checksum '%s'", checksum)
+	}
+	if _, err = hex.DecodeString(digest); err != nil {
+		return "", "", fmt.Errorf("invalid checksum '%s'", checksum)
+	}
+	return
+}
+
+// fileChecksum returns the hex digest of the file `fileName` using `algorithm`, and the file's size. If `algorithm`
+// is "", the file is not read, and only its size is returned.
+func fileChecksum(fileName string, algorithm string) (string, int64, error) {
+	if algorithm == "" {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return "", 0, err
+		}
+		return "", info.Size(), nil
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := checksumHashes[algorithm]()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("Error reading `%s`: %v", fileName, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// VerifyChecksums checks the checksum and size (if given) of each file in the manifest of `dataDirectory`, using the
+// manifest's "checksum" and "size" fields. Files without a checksum are not read, with a warning. All mismatches are returned at once, in a *ManifestError.
+func VerifyChecksums(dataDirectory *datadirectory.DataDirectory) ([]*VerifiedFile, error) {
+	return verifyChecksums(dataDirectory.RecordMaps, func(fileName string, algorithm string) (string, int64, error) {
+		return fileChecksum(path.Join(dataDirectory.DirPath, fileName), algorithm)
+	})
+}
+
+// verifyChecksums does the work for VerifyChecksums, for the manifest entries `recordMaps`, using `checksum` to obtain the
+// hex digest and size of each file, or only the size if the algorithm is "".
+func verifyChecksums(recordMaps []map[string]string, checksum func(fileName string, algorithm string) (string, int64, error)) ([]*VerifiedFile, error) {
+	var (
+		files    []*VerifiedFile
+		problems []string
+	)
+	for _, m := range recordMaps {
+		fileName := m["filename"]
+
+		algorithm, expected := "", ""
+		if m["checksum"] != "" {
+			var err error
+			if algorithm, expected, err = parseChecksum(m["checksum"]); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", fileName, err))
+				continue
+			}
+		} else {
+			log.Warn(fmt.Sprintf("No checksum for %s in the manifest; it cannot be verified", fileName))
+		}
+
+		actual, size, err := checksum(fileName, algorithm)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", fileName, err))
+			continue
+		}
+		if expected != "" && actual != expected {
+			problems = append(problems, fmt.Sprintf("%s: %s checksum is %s, not %s as in the manifest", fileName, algorithm, actual, expected))
+		}
+		if m["size"] != "" {
+			if expectedSize, err := strconv.ParseInt(m["size"], 10, 64); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid size '%s'", fileName, m["size"]))
+			} else if size != expectedSize {
+				problems = append(problems, fmt.Sprintf("%s: size is %d bytes, not %d as in the manifest", fileName, size, expectedSize))
+			}
+		}
+		f := &VerifiedFile{Table: m["table"], FileName: fileName, Size: size}
+		if algorithm != "" {
+			f.Checksum = algorithm + ":" + actual
+		}
+		files = append(files, f)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ManifestError{Problems: problems}
+	}
+	return files, nil
+}
+
+// SQL creating the `load_log` table, which records the provenance of each loaded file.
+const createLoadLogSql = `CREATE TABLE IF NOT EXISTS load_log (
+	table_name VARCHAR(255) NOT NULL,
+	file_name VARCHAR(1024) NOT NULL,
+	checksum VARCHAR(255) NOT NULL,
+	size BIGINT NOT NULL,
+	model VARCHAR(255) NOT NULL,
+	model_version VARCHAR(50) NOT NULL,
+	tool_version VARCHAR(50) NOT NULL,
+	datetime TIMESTAMP NOT NULL
+)`
+
+// LoadLogEntry is a row of the `load_log` table.
+type LoadLogEntry struct {
+	VerifiedFile
+	Model        string
+	ModelVersion string
+	ToolVersion  string
+	Datetime     time.Time
+}
+
+// recordLoadedFiles adds a row for each of `files` to the `load_log` table, creating the table if necessary, using the
+// Database's Executor like its DDL operations.
+func (d *Database) recordLoadedFiles(files []*VerifiedFile) error {
+	e := d.executor()
+	if err := executeSQL(e, createLoadLogSql); err != nil {
+		return err
+	}
+	sql := "INSERT INTO load_log (table_name, file_name, checksum, size, model, model_version, tool_version, datetime) VALUES ($1, $2, $3, $4, $5, $6, $7, now())"
+	for _, f := range files {
+		if err := executeSQLArgs(e, sql, f.Table, f.FileName, f.Checksum, f.Size, d.Model, d.ModelVersion, toolVersion); err != nil {
+			return fmt.Errorf("Error recording load of `%s` in load_log: %v", f.FileName, err)
+		}
+	}
+	return nil
+}
+
+// LoadLog returns the rows of the `load_log` table for the data model, oldest first.
+func (d *Database) LoadLog() ([]*LoadLogEntry, error) {
+	db, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT table_name, file_name, checksum, size, model, model_version, tool_version, datetime FROM load_log WHERE model = $1 ORDER BY datetime"
+	rows, err := db.Query(query, d.Model)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading load_log: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*LoadLogEntry
+	for rows.Next() {
+		e := new(LoadLogEntry)
+		if err = rows.Scan(&e.Table, &e.FileName, &e.Checksum, &e.Size, &e.Model, &e.ModelVersion, &e.ToolVersion, &e.Datetime); err != nil {
+			return nil, fmt.Errorf("Error reading load_log: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
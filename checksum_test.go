@@ -0,0 +1,78 @@
+package database
+
+import (
+	"github.com/infomodels/datadirectory"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "person.csv"), []byte("person_id\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sha256, _, err := fileChecksum(filepath.Join(dir, "person.csv"), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5, _, _ := fileChecksum(filepath.Join(dir, "person.csv"), "md5")
+
+	dataDirectory := &datadirectory.DataDirectory{DirPath: dir, RecordMaps: []map[string]string{
+		{"table": "person", "filename": "person.csv", "checksum": strings.ToUpper(sha256), "size": "12"},
+		{"table": "person", "filename": "person.csv", "checksum": "md5:" + md5},
+		{"table": "person", "filename": "person.csv"},
+	}}
+	files, err := VerifyChecksums(dataDirectory)
+	if err != nil {
+		t.Fatalf("VerifyChecksums failed: %v", err)
+	}
+	expected := []*VerifiedFile{
+		{Table: "person", FileName: "person.csv", Checksum: "sha256:" + sha256, Size: 12},
+		{Table: "person", FileName: "person.csv", Checksum: "md5:" + md5, Size: 12},
+		{Table: "person", FileName: "person.csv", Size: 12},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Unexpected verified files: %+v", files)
+	}
+
+	dataDirectory.RecordMaps = []map[string]string{
+		{"table": "person", "filename": "person.csv", "checksum": md5[1:] + "0"},
+		{"table": "person", "filename": "person.csv", "checksum": sha256, "size": "13"},
+		{"table": "person", "filename": "person.csv", "checksum": "crc32:1234"},
+		{"table": "person", "filename": "missing.csv", "checksum": sha256},
+	}
+	_, err = VerifyChecksums(dataDirectory)
+	manifestErr, ok := err.(*ManifestError)
+	if !ok || len(manifestErr.Problems) != 4 {
+		t.Fatalf("Expected 4 problems, got %v", err)
+	}
+	for i, expected := range []string{"md5 checksum is", "size is 12 bytes, not 13", "unrecognized checksum", "missing.csv:"} {
+		if !strings.Contains(manifestErr.Problems[i], expected) {
+			t.Errorf("Problem %q does not mention %q", manifestErr.Problems[i], expected)
+		}
+	}
+}
+
+func TestRecordLoadedFiles(t *testing.T) {
+	e := new(RecordingExecutor)
+	d := &Database{Model: "pedsnet", ModelVersion: "2.2.0", Executor: e}
+	if err := d.recordLoadedFiles([]*VerifiedFile{{Table: "concept", FileName: "concept.csv", Checksum: "sha256:abc", Size: 42}}); err != nil {
+		t.Fatalf("recordLoadedFiles failed: %v", err)
+	}
+	statements := e.Statements()
+	if len(statements) != 2 || !strings.HasPrefix(statements[0], "CREATE TABLE IF NOT EXISTS load_log") {
+		t.Fatalf("Unexpected statements: %q", statements)
+	}
+	if expected := "VALUES ('concept', 'concept.csv', 'sha256:abc', 42, 'pedsnet', '2.2.0', '" + toolVersion + "', now())"; !strings.HasSuffix(statements[1], expected) {
+		t.Errorf("Unexpected insert: %s", statements[1])
+	}
+}
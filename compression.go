@@ -0,0 +1,74 @@
+package database
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Decompressors for compressed data files, by file name extension.
+var decompressors = map[string]func(r io.Reader) (io.Reader, error){
+	".gz":  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	".bz2": func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil },
+	".zst": func(r io.Reader) (io.Reader, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+	".xz": func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+}
+
+// dataFileReader is a data file being read, possibly through a decompressor.
+type dataFileReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the decompressor, if it needs closing, and the file.
+func (r *dataFileReader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// openDataFile opens the data file `fileName` for reading, decompressing it on the fly if its extension is .gz, .bz2,
+// .zst or .xz.
+func openDataFile(fileName string) (io.ReadCloser, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return decompressedReader(fileName, f)
+}
+
+// decompressedReader returns a reader of the data in `r`, read from `fileName`, decompressed according to the file
+// name's extension. Closing the reader closes `r`.
+func decompressedReader(fileName string, r io.ReadCloser) (io.ReadCloser, error) {
+	decompressor, ok := decompressors[strings.ToLower(filepath.Ext(fileName))]
+	if !ok {
+		return r, nil
+	}
+	d, err := decompressor(r)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("Error decompressing `%s`: %v", fileName, err)
+	}
+	reader := &dataFileReader{Reader: d}
+	if c, ok := d.(io.Closer); ok {
+		reader.closers = append(reader.closers, c)
+	}
+	reader.closers = append(reader.closers, r)
+	return reader, nil
+}
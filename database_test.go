@@ -2,13 +2,18 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/infomodels/database/dmsatest"
+	"github.com/infomodels/database/pgtest"
 	"github.com/infomodels/datadirectory"
 	"github.com/infomodels/datapackage"
 	"io/ioutil"
+	"net/http/httptest"
 	//	log "github.com/Sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -16,43 +21,62 @@ import (
 type TestEnv struct {
 	DatabaseUrl, SearchPath, DmsaUrl, DmUrl, Model, ModelVersion, TempDir string
 	PedsnetVocabDataDir                                                   *datadirectory.DataDirectory
+
+	dmsaServer *dmsatest.Server // Local fake DMSA service, unless DT_DMSA_URL is set
+	dmServer   *httptest.Server // Local fake data-models-service, unless DT_DM_URL is set
+	cluster    *pgtest.Cluster  // Local PostgreSQL cluster, unless DT_DATABASE_URL is set
 }
 
 // A zip file containing some test vocab data
-var pedsnetVocabUrl = "http://github.com/infomodels/database/test_resources/pedsnet_vocab.tar.gz"
+var pedsnetVocabUrl = "test_resources/pedsnet_vocab.tar.gz"
 
 // NewTestEnv initializes the test environment from environment variables.
 //
-// Call the Cleanup() method afterwards to remove temp files.
+// The Cleanup() method, which removes temp files and stops local servers, is
+// registered with t.Cleanup, so it runs even if the test fails.
 //
-// The `DT_DATABASE_URL` variable is required.
+// The optional variable `DT_DATABASE_URL` gives a database to use instead of a
+// throwaway local PostgreSQL cluster (see pgtest), which needs the PostgreSQL
+// server binaries and, when the tests run as root, an unprivileged user named by
+// `PGTEST_USER` to run the cluster as. Without either, the test is skipped.
 //
-// The optional variable `DT_DMSA_URL` allows overriding the default
-// of http://data-models-sqlalchemy.research.chop.edu/.
+// The optional variable `DT_DMSA_URL` gives a data-models-sqlalchemy service to use
+// instead of a local fake serving the DDL in test_resources/dmsa (see dmsatest).
 //
-// The optional variable `DT_DM_URL` allows overriding the default
-// of http://data-models-service.research.chop.edu/.
+// The optional variable `DT_DM_URL` gives a data-models-service to use instead of a
+// local fake serving test_resources/pedsnet_2.2.0_model.json (see newDataModelServer).
 //
 // The optional variable `DT_VOCAB_URL` allows overriding the default of
-// http://github.com/infomodels/database/test_resources/pedsnet_vocab.tar.gz.
-// `DT_VOCAB_URL` can also be an absolute local file name.
+// test_resources/pedsnet_vocab.tar.gz. `DT_VOCAB_URL` can be an http URL or a local file name.
 func NewTestEnv(t *testing.T) *TestEnv {
 
 	te := new(TestEnv)
+	t.Cleanup(te.Cleanup)
 
 	if te.DatabaseUrl = os.Getenv("DT_DATABASE_URL"); te.DatabaseUrl == "" {
-		t.Error("DT_DATABASE_URL environment variable required for testing")
-		t.FailNow()
+		cluster, err := pgtest.Start()
+		if errors.Is(err, pgtest.ErrUnavailable) {
+			t.Skip(fmt.Sprintf("DT_DATABASE_URL environment variable not set, and a local PostgreSQL cluster cannot be started: %v", err))
+		} else if err != nil {
+			t.Error(fmt.Sprintf("DT_DATABASE_URL environment variable not set, and a local PostgreSQL cluster cannot be started: %v", err))
+			t.FailNow()
+		}
+		te.cluster = cluster
+		te.DatabaseUrl = cluster.URL
+		// Loading shells out to psql.
+		t.Setenv("PATH", cluster.BinDir+string(os.PathListSeparator)+os.Getenv("PATH"))
 	}
 
 	te.DmsaUrl = os.Getenv("DT_DMSA_URL")
-	if te.DmUrl == "" {
-		te.DmUrl = "http://data-models-sqlalchemy.research.chop.edu/"
+	if te.DmsaUrl == "" {
+		te.dmsaServer = dmsatest.NewDirServer("test_resources/dmsa")
+		te.DmsaUrl = te.dmsaServer.URL
 	}
 
 	te.DmUrl = os.Getenv("DT_DM_URL")
 	if te.DmUrl == "" {
-		te.DmUrl = "http://data-models-service.research.chop.edu/"
+		te.dmServer = newDataModelServer()
+		te.DmUrl = te.dmServer.URL
 	}
 
 	var err error
@@ -108,6 +132,15 @@ func NewTestEnv(t *testing.T) *TestEnv {
 
 func (te *TestEnv) Cleanup() {
 	os.RemoveAll(te.TempDir)
+	if te.dmsaServer != nil {
+		te.dmsaServer.Close()
+	}
+	if te.dmServer != nil {
+		te.dmServer.Close()
+	}
+	if te.cluster != nil {
+		te.cluster.Stop()
+	}
 }
 
 // execSql executes a non-SELECT SQL statement
@@ -204,7 +237,7 @@ func instantiateDataModel(t *testing.T, te *TestEnv, dataModel string, dataModel
 		err error
 		d   *Database
 	)
-	if d, err = Open(dataModel, dataModelVersion, te.DatabaseUrl, searchPath, te.DmsaUrl, "", ""); err != nil {
+	if d, err = OpenWithOptions(&Options{Model: dataModel, ModelVersion: dataModelVersion, DatabaseUrl: te.DatabaseUrl, SearchPath: searchPath, DmsaUrl: te.DmsaUrl, DmUrl: te.DmUrl}); err != nil {
 		t.Error(fmt.Sprintf("Open failed: %v", err))
 		t.FailNow()
 	}
@@ -253,7 +286,7 @@ func loadDataModel(t *testing.T, te *TestEnv, dataModel string, dataModelVersion
 		d             *Database
 		primarySchema string
 	)
-	if d, err = Open(dataModel, dataModelVersion, te.DatabaseUrl, searchPath, te.DmsaUrl, "", ""); err != nil {
+	if d, err = OpenWithOptions(&Options{Model: dataModel, ModelVersion: dataModelVersion, DatabaseUrl: te.DatabaseUrl, SearchPath: searchPath, DmsaUrl: te.DmsaUrl, DmUrl: te.DmUrl}); err != nil {
 		t.Error(fmt.Sprintf("Open failed: %v", err))
 		t.FailNow()
 	}
@@ -313,7 +346,7 @@ func destroyDataModel(t *testing.T, te *TestEnv, dataModel string, dataModelVers
 		err error
 		d   *Database
 	)
-	if d, err = Open(dataModel, dataModelVersion, te.DatabaseUrl, searchPath, te.DmsaUrl, "", ""); err != nil {
+	if d, err = OpenWithOptions(&Options{Model: dataModel, ModelVersion: dataModelVersion, DatabaseUrl: te.DatabaseUrl, SearchPath: searchPath, DmsaUrl: te.DmsaUrl, DmUrl: te.DmUrl}); err != nil {
 		t.Error(fmt.Sprintf("Open failed: %v", err))
 		t.FailNow()
 	}
@@ -359,6 +392,43 @@ func TestPedsnetInParts(t *testing.T) {
 	instantiatePedsnetCore(t, te)
 	destroyPedsnetCore(t, te)
 	destroyPedsnetVocab(t, te)
+}
 
-	te.Cleanup()
+func TestNew(t *testing.T) {
+	d, err := New(&Options{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: fixtureSource(fixtureTables)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if _, err = d.VersionHistory(); err == nil || !strings.Contains(err.Error(), "no connection") {
+		t.Errorf("Expected VersionHistory to fail without a connection, got %v", err)
+	}
+	if _, err = d.InstalledModelVersion(); err == nil || !strings.Contains(err.Error(), "no connection") {
+		t.Errorf("Expected InstalledModelVersion to fail without a connection, got %v", err)
+	}
+	if _, err = d.LoadLog(); err == nil || !strings.Contains(err.Error(), "no connection") {
+		t.Errorf("Expected LoadLog to fail without a connection, got %v", err)
+	}
+}
+
+func TestIsTableSelected(t *testing.T) {
+	// Without patterns, every table is selected.
+	d, _ := newRecordingDatabase()
+	if !d.isTableSelected("person") || !d.isTableSelected("visit_payer") {
+		t.Error("Expected every table to be selected without patterns")
+	}
+	stmts, err := dmsaSql(d, "ddl", "tables", normalPatternsType{`CREATE TABLE.* (\w+) \(`})
+	if err != nil || len(stmts) != 2 {
+		t.Errorf("Expected the DDL of both tables without patterns, got %q, %v", stmts, err)
+	}
+
+	d.includeTables = regexp.MustCompile("^person$")
+	if !d.isTableSelected("person") || d.isTableSelected("visit_payer") {
+		t.Error("Expected only person to be included")
+	}
+	d.includeTables, d.excludeTables = nil, regexp.MustCompile("^person$")
+	if d.isTableSelected("person") || !d.isTableSelected("visit_payer") {
+		t.Error("Expected person to be excluded")
+	}
 }
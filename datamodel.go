@@ -0,0 +1,195 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DataModel is a data model definition as served by data-models-service (https://github.com/chop-dbhi/data-models-service),
+// e.g. from http://data-models-service.research.chop.edu/models/pedsnet/2.2.0.
+type DataModel struct {
+	Name        string                `json:"name"`
+	Version     string                `json:"version"`
+	Tables      []*DataModelTable     `json:"tables"`
+	Constraints *DataModelConstraints `json:"constraints"`
+	Indexes     []*DataModelIndex     `json:"indexes"`
+
+	// Some versions of the service nest constraints and indexes in a schema object.
+	Schema *struct {
+		Constraints *DataModelConstraints `json:"constraints"`
+		Indexes     []*DataModelIndex     `json:"indexes"`
+	} `json:"schema"`
+}
+
+// DataModelTable is a table definition within a DataModel.
+type DataModelTable struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Fields      []*DataModelField `json:"fields"`
+}
+
+// DataModelField is a field (column) definition within a DataModelTable.
+type DataModelField struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`      // Abstract type, e.g. "integer", "string", "date", "decimal".
+	Length      int    `json:"length"`    // Maximum length of strings, or 0.
+	Precision   int    `json:"precision"` // Precision of decimals, or 0.
+	Scale       int    `json:"scale"`     // Scale of decimals, or 0.
+	Required    bool   `json:"required"`  // Whether the field is NOT NULL.
+}
+
+// DataModelConstraints holds the constraints of a DataModel.
+type DataModelConstraints struct {
+	PrimaryKeys []*DataModelPrimaryKey `json:"primary_keys"`
+	ForeignKeys []*DataModelForeignKey `json:"foreign_keys"`
+}
+
+// DataModelPrimaryKey is a primary key constraint within a DataModel.
+type DataModelPrimaryKey struct {
+	Name   string   `json:"name"`
+	Table  string   `json:"table"`
+	Fields []string `json:"fields"`
+}
+
+// DataModelForeignKey is a single-field foreign key constraint within a DataModel.
+type DataModelForeignKey struct {
+	Name        string `json:"name"`
+	SourceTable string `json:"source_table"`
+	SourceField string `json:"source_field"`
+	TargetTable string `json:"target_table"`
+	TargetField string `json:"target_field"`
+}
+
+// DataModelIndex is an index within a DataModel.
+type DataModelIndex struct {
+	Name   string   `json:"name"`
+	Table  string   `json:"table"`
+	Fields []string `json:"fields"`
+	Unique bool     `json:"unique"`
+}
+
+// FetchDataModel fetches version `version` of data model `model` from the data-models-service at `dmUrl`.
+func FetchDataModel(dmUrl string, model string, version string) (*DataModel, error) {
+	url := joinUrlPath(dmUrl, fmt.Sprintf("/models/%s/%s", model, version))
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot access data-models-service at %v: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("Data-models-service (%v) returned error: %v", url, http.StatusText(response.StatusCode))
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading body from %v: %v", url, err)
+	}
+
+	m := new(DataModel)
+	if err = json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("Error parsing data model from %v: %v", url, err)
+	}
+	if m.Schema != nil {
+		if m.Constraints == nil {
+			m.Constraints = m.Schema.Constraints
+		}
+		if m.Indexes == nil {
+			m.Indexes = m.Schema.Indexes
+		}
+	}
+	if m.Constraints == nil {
+		m.Constraints = new(DataModelConstraints)
+	}
+	return m, nil
+}
+
+// Table returns the named table, or nil if the model has no such table.
+func (m *DataModel) Table(name string) *DataModelTable {
+	for _, t := range m.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Pattern matching identifier field names, e.g. `person_id` or `fact_id_1`.
+var clinicalIdentifierPattern = regexp.MustCompile(`_id(?:_\d+)?$`)
+
+// VocabularyTables returns the names of the model's vocabulary tables, sorted.
+//
+// Tables are classified by their foreign keys: a vocabulary table has at least one foreign key, all of which
+// refer to other vocabulary tables, and no identifier fields other than keys and concept identifiers (unlike,
+// say, `fact_id_1`). Clinical tables refer, directly or indirectly, to tables without foreign keys such as `location`.
+func (m *DataModel) VocabularyTables() []string {
+	keys := make(map[string]map[string]bool) // Table to primary and foreign key fields
+	references := make(map[string]map[string]bool)
+	for _, t := range m.Tables {
+		keys[t.Name] = make(map[string]bool)
+		references[t.Name] = make(map[string]bool)
+	}
+	for _, pk := range m.Constraints.PrimaryKeys {
+		for _, field := range pk.Fields {
+			if keys[pk.Table] != nil {
+				keys[pk.Table][field] = true
+			}
+		}
+	}
+	for _, fk := range m.Constraints.ForeignKeys {
+		if keys[fk.SourceTable] == nil {
+			continue
+		}
+		keys[fk.SourceTable][fk.SourceField] = true
+		if fk.TargetTable != fk.SourceTable {
+			references[fk.SourceTable][fk.TargetTable] = true
+		}
+	}
+
+	// Start with every table having a reference and no clinical identifiers, then remove tables referring to non-vocabulary tables until nothing changes.
+	vocab := make(map[string]bool)
+	for _, t := range m.Tables {
+		if len(references[t.Name]) == 0 {
+			continue
+		}
+		clinical := false
+		for _, f := range t.Fields {
+			if clinicalIdentifierPattern.MatchString(f.Name) && !strings.Contains(f.Name, "concept") && !keys[t.Name][f.Name] {
+				clinical = true
+				break
+			}
+		}
+		if !clinical {
+			vocab[t.Name] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for table := range vocab {
+			for target := range references[table] {
+				if !vocab[target] {
+					delete(vocab, table)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var tables []string
+	for table := range vocab {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// newDataModelServer returns a fake data-models-service serving version 2.2.0 of the pedsnet model from test_resources.
+func newDataModelServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/pedsnet/2.2.0" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "test_resources/pedsnet_2.2.0_model.json")
+	}))
+}
+
+func TestVocabularyTables(t *testing.T) {
+	server := newDataModelServer()
+	defer server.Close()
+
+	m, err := FetchDataModel(server.URL, "pedsnet", "2.2.0")
+	if err != nil {
+		t.Fatalf("FetchDataModel failed: %v", err)
+	}
+
+	expected := []string{"concept", "concept_ancestor", "concept_class", "concept_relationship", "concept_synonym", "domain", "drug_strength", "relationship", "source_to_concept_map", "vocabulary"}
+	if tables := m.VocabularyTables(); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Unexpected vocabulary tables: %v", tables)
+	}
+
+	if _, err = FetchDataModel(server.URL, "pedsnet", "9.9.9"); err == nil {
+		t.Error("Expected an error for an unknown model version")
+	}
+}
+
+func TestDeriveTableGroup(t *testing.T) {
+	server := newDataModelServer()
+	defer server.Close()
+
+	g, err := resolveTableGroup("pedsnet-core", "2.2.0", server.URL)
+	if err != nil || g == nil || g.Exclude == pedsnetVocabTablesPat {
+		t.Fatalf("Expected a derived pedsnet-core group for 2.2.0, got %+v (%v)", g, err)
+	}
+	exclude := regexp.MustCompile(g.Exclude)
+	for _, table := range []string{"concept", "drug_strength", "vocabulary"} {
+		if !exclude.MatchString(table) {
+			t.Errorf("Derived pedsnet-core group should exclude %s", table)
+		}
+	}
+	for _, table := range []string{"person", "fact_relationship", "location", "measurement_organism"} {
+		if exclude.MatchString(table) {
+			t.Errorf("Derived pedsnet-core group should include %s", table)
+		}
+	}
+
+	if g, err = deriveTableGroup("pedsnet-results", "2.2.0", server.URL); g != nil || err != nil {
+		t.Errorf("Group results should not be derivable, got %+v (%v)", g, err)
+	}
+
+	// Offline, the default group is used.
+	server.Close()
+	if g, err = resolveTableGroup("pedsnet-core", "2.2.0", server.URL); err != nil || g == nil || g.Exclude != pedsnetVocabTablesPat {
+		t.Errorf("Expected the default pedsnet-core group for 2.2.0 offline, got %+v (%v)", g, err)
+	}
+}
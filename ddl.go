@@ -0,0 +1,237 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// columnDefinition describes a column parsed from a CREATE TABLE statement.
+type columnDefinition struct {
+	name         string
+	sqlType      string // SQL type, e.g. "VARCHAR(255)" or "NUMERIC(20, 5)"
+	notNull      bool
+	defaultValue string // DEFAULT expression, e.g. "0" or "now()", or "" if there is none
+}
+
+// definition returns the column definition as it would appear in a CREATE TABLE or ADD COLUMN statement.
+func (c *columnDefinition) definition() string {
+	def := fmt.Sprintf("%s %s", c.name, c.sqlType)
+	if c.defaultValue != "" {
+		def += " DEFAULT " + c.defaultValue
+	}
+	if c.notNull {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// tableDefinition describes a table parsed from a CREATE TABLE statement.
+type tableDefinition struct {
+	name        string
+	columns     []*columnDefinition
+	constraints []string // Table-level clauses, e.g. "PRIMARY KEY (person_id)"
+	sql         string   // The CREATE TABLE statement itself
+}
+
+// column returns the named column, or nil if the table has no such column.
+func (t *tableDefinition) column(name string) *columnDefinition {
+	for _, c := range t.columns {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Type categories of SQL types, by type name without size or precision.
+var sqlTypeCategories = map[string]string{
+	"SMALLINT": "integer", "INTEGER": "integer", "INT": "integer", "BIGINT": "integer",
+	"NUMERIC": "numeric", "DECIMAL": "numeric",
+	"REAL": "float", "FLOAT": "float", "DOUBLE PRECISION": "float",
+	"VARCHAR": "text", "CHARACTER VARYING": "text", "CHAR": "text", "CHARACTER": "text", "TEXT": "text",
+	"DATE":      "date",
+	"TIMESTAMP": "timestamp", "TIMESTAMP WITHOUT TIME ZONE": "timestamp", "TIMESTAMP WITH TIME ZONE": "timestamp",
+	"TIME": "time", "TIME WITHOUT TIME ZONE": "time",
+	"BOOLEAN": "boolean",
+}
+
+var sqlTypeSizePattern = regexp.MustCompile(`\s*\([^)]*\)`)
+
+// baseType returns the column's type without size or precision, in upper case, e.g. "VARCHAR" for varchar(255).
+func (c *columnDefinition) baseType() string {
+	return strings.ToUpper(sqlTypeSizePattern.ReplaceAllString(c.sqlType, ""))
+}
+
+// category returns the column's type category, e.g. "integer" for INTEGER or BIGINT, "text" for VARCHAR(255),
+// or "" if the type is not recognized.
+func (c *columnDefinition) category() string {
+	return sqlTypeCategories[c.baseType()]
+}
+
+// entityDefinition describes an index or constraint parsed from its creation SQL.
+type entityDefinition struct {
+	name  string
+	table string
+	sql   string
+}
+
+var createTablePattern = regexp.MustCompile(`(?s)^CREATE TABLE\s+(\w+)\s*\((.*)\)\s*$`)
+
+// Keywords that end the type portion of a column definition.
+var columnTypeTerminators = map[string]bool{"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "UNIQUE": true, "REFERENCES": true, "CHECK": true, "CONSTRAINT": true}
+
+// Keywords that introduce a table-level clause rather than a column in a CREATE TABLE body.
+var tableClausePrefixes = []string{"PRIMARY KEY", "CONSTRAINT ", "UNIQUE", "FOREIGN KEY", "CHECK"}
+
+// normalizeSql collapses runs of whitespace so that statements can be compared textually.
+func normalizeSql(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// splitTopLevel splits `s` on commas that are not nested within parentheses.
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseColumnDefinition parses a single column definition such as "person_id INTEGER NOT NULL" or
+// "active BOOLEAN DEFAULT true". Column-level REFERENCES and CHECK clauses are not kept, so upgrades do not change them;
+// data-models-sqlalchemy DDL declares foreign keys as separate constraints, which upgrades do compare.
+func parseColumnDefinition(def string) (*columnDefinition, error) {
+	fields := strings.Fields(def)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("Cannot parse column definition `%s`", def)
+	}
+	c := &columnDefinition{name: fields[0]}
+	var typeParts []string
+	for i, field := range fields[1:] {
+		upper := strings.ToUpper(field)
+		if columnTypeTerminators[upper] {
+			rest := fields[i+1:]
+			restUpper := strings.ToUpper(strings.Join(rest, " "))
+			c.notNull = strings.Contains(restUpper, "NOT NULL") || strings.Contains(restUpper, "PRIMARY KEY")
+			c.defaultValue = columnDefault(rest)
+			break
+		}
+		typeParts = append(typeParts, field)
+	}
+	c.sqlType = strings.Join(typeParts, " ")
+	return c, nil
+}
+
+// columnDefault returns the DEFAULT expression in the fields of a column definition after its type, or "" if there is
+// none.
+func columnDefault(fields []string) string {
+	for i, field := range fields {
+		if strings.ToUpper(field) != "DEFAULT" {
+			continue
+		}
+		var expr []string
+		for _, field := range fields[i+1:] {
+			if columnTypeTerminators[strings.ToUpper(field)] {
+				break
+			}
+			expr = append(expr, field)
+		}
+		return strings.Join(expr, " ")
+	}
+	return ""
+}
+
+// parseTableDefinition parses a CREATE TABLE statement. It returns nil if `stmt` is not a CREATE TABLE statement.
+func parseTableDefinition(stmt string) (*tableDefinition, error) {
+	stmt = strings.TrimSpace(stmt)
+	matches := createTablePattern.FindStringSubmatch(stmt)
+	if matches == nil {
+		return nil, nil
+	}
+	t := &tableDefinition{name: matches[1], sql: stmt}
+	for _, part := range splitTopLevel(matches[2]) {
+		part = normalizeSql(part)
+		if part == "" {
+			continue
+		}
+		isClause := false
+		for _, prefix := range tableClausePrefixes {
+			if strings.HasPrefix(strings.ToUpper(part), prefix) {
+				isClause = true
+				break
+			}
+		}
+		if isClause {
+			t.constraints = append(t.constraints, part)
+			continue
+		}
+		c, err := parseColumnDefinition(part)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing table `%s`: %v", t.name, err)
+		}
+		t.columns = append(t.columns, c)
+	}
+	return t, nil
+}
+
+// parseTableDefinitions parses all CREATE TABLE statements in `stmts`, returning the tables keyed by name.
+// Statements other than CREATE TABLE are ignored.
+func parseTableDefinitions(stmts []string) (map[string]*tableDefinition, error) {
+	tables := make(map[string]*tableDefinition)
+	for _, stmt := range stmts {
+		t, err := parseTableDefinition(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			tables[t.name] = t
+		}
+	}
+	return tables, nil
+}
+
+// tableDefinitions returns the definitions of the tables of the data model, keyed by name.
+func (d *Database) tableDefinitions() (map[string]*tableDefinition, error) {
+	stmts, err := rawDmsaSql(d, d.ModelVersion, "ddl", "tables")
+	if err != nil {
+		return nil, err
+	}
+	return parseTableDefinitions(stmts)
+}
+
+// parseEntityDefinitions parses index or constraint creation statements in `stmts`, returning them keyed by entity name.
+// `patterns` supplies the table and entity name patterns for the creation SQL; statements matching neither are ignored.
+func parseEntityDefinitions(stmts []string, patterns mapPatternsType) (map[string]*entityDefinition, error) {
+	tablePattern := regexp.MustCompile(patterns.tableCreate)
+	entityPattern := regexp.MustCompile(patterns.entityCreate)
+
+	entities := make(map[string]*entityDefinition)
+	for _, stmt := range stmts {
+		stmt = strings.TrimSpace(stmt)
+		tableMatches := tablePattern.FindStringSubmatch(stmt)
+		if tableMatches == nil {
+			continue
+		}
+		entityMatches := entityPattern.FindStringSubmatch(stmt)
+		if entityMatches == nil {
+			return nil, fmt.Errorf("patterns.entityCreate `%s` does not match against `%s`", patterns.entityCreate, stmt)
+		}
+		entities[entityMatches[1]] = &entityDefinition{name: entityMatches[1], table: tableMatches[1], sql: stmt}
+	}
+	return entities, nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DDLSource provides the SQL statements that create or drop a data model's tables, indexes or constraints.
+//
+// `ddlOperator` is "ddl" (i.e. create) or "drop"; `ddlOperand` is "tables", "indexes" or "constraints".
+// Statements are returned without terminating semicolons. DDL rules and extensions are applied by the
+// Database to whatever its source returns.
+type DDLSource interface {
+	Statements(model string, version string, dialect string, ddlOperator string, ddlOperand string) ([]string, error)
+}
+
+// splitSql splits a script into statements on ";", dropping blank statements.
+func splitSql(script string) []string {
+	var stmts []string
+	for _, stmt := range strings.Split(script, ";") {
+		if strings.TrimSpace(stmt) != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// DmsaSource obtains DDL from a data-models-sqlalchemy web service, e.g.
+// https://data-models-sqlalchemy.research.chop.edu/{Model}/{ModelVersion}/ddl/postgresql/tables/.
+type DmsaSource struct {
+	Url string // data-models-sqlalchemy base URL, or "" for the default.
+}
+
+// Statements implements DDLSource.
+func (s *DmsaSource) Statements(model string, version string, dialect string, ddlOperator string, ddlOperand string) ([]string, error) {
+	dmsaUrl := s.Url
+	if dmsaUrl == "" {
+		dmsaUrl = defaultDmsaUrl
+	}
+	url := joinUrlPath(dmsaUrl, fmt.Sprintf("/%s/%s/%s/%s/%s/", model, version, ddlOperator, dialect, ddlOperand))
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting %v: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("Data-models-sqlalchemy web service (%v) returned error: %v", url, http.StatusText(response.StatusCode))
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading body from %v: %v", url, err)
+	}
+	return splitSql(string(body)), nil
+}
+
+// FSSource obtains DDL from SQL files laid out like the data-models-sqlalchemy URLs, i.e.
+// `{model}/{version}/{ddl|drop}/{dialect}/{tables|indexes|constraints}.sql`, in a file system such
+// as a directory (see NewDirSource) or an `embed.FS` bundled with a program.
+type FSSource struct {
+	FS fs.FS
+}
+
+// NewDirSource returns a DDLSource reading SQL files from the directory `dir`, laid out as for FSSource.
+func NewDirSource(dir string) *FSSource {
+	return &FSSource{FS: os.DirFS(dir)}
+}
+
+// Statements implements DDLSource.
+func (s *FSSource) Statements(model string, version string, dialect string, ddlOperator string, ddlOperand string) ([]string, error) {
+	name := path.Join(model, version, ddlOperator, dialect, ddlOperand+".sql")
+	data, err := fs.ReadFile(s.FS, name)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read DDL file `%s`: %v", name, err)
+	}
+	return splitSql(string(data)), nil
+}
+
+// NativeSource generates DDL (see GenerateDDL) from data model definitions obtained from a data-models-service.
+type NativeSource struct {
+	DmUrl string // data-models-service base URL.
+
+	mutex  sync.Mutex
+	models map[string]*DataModel // Fetched models, keyed by "{model}/{version}"
+}
+
+// Statements implements DDLSource.
+func (s *NativeSource) Statements(model string, version string, dialect string, ddlOperator string, ddlOperand string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := model + "/" + version
+	m, ok := s.models[key]
+	if !ok {
+		var err error
+		if m, err = FetchDataModel(s.DmUrl, model, version); err != nil {
+			return nil, err
+		}
+		if s.models == nil {
+			s.models = make(map[string]*DataModel)
+		}
+		s.models[key] = m
+	}
+	return GenerateDDL(m, dialect, ddlOperator, ddlOperand)
+}
+
+// DiffDDL compares the statements obtained from sources `a` and `b`, ignoring whitespace and order. It returns the
+// statements only `a` provides and those only `b` provides, each sorted, in normalized form.
+func DiffDDL(a DDLSource, b DDLSource, model string, version string, dialect string, ddlOperator string, ddlOperand string) (onlyA []string, onlyB []string, err error) {
+	stmtsA, err := a.Statements(model, version, dialect, ddlOperator, ddlOperand)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmtsB, err := b.Statements(model, version, dialect, ddlOperator, ddlOperand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, stmt := range stmtsA {
+		counts[normalizeSql(stmt)]++
+	}
+	for _, stmt := range stmtsB {
+		counts[normalizeSql(stmt)]--
+	}
+	for stmt, count := range counts {
+		for ; count > 0; count-- {
+			onlyA = append(onlyA, stmt)
+		}
+		for ; count < 0; count++ {
+			onlyB = append(onlyB, stmt)
+		}
+	}
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+	return onlyA, onlyB, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var sourceTablesSql = `
+CREATE TABLE person (
+	person_id INTEGER NOT NULL,
+	PRIMARY KEY (person_id)
+);
+
+CREATE TABLE version_history (
+	operation VARCHAR(100),
+	dms_version VARCHAR(16)
+);
+`
+
+var sourceTables = []string{`
+CREATE TABLE person (
+	person_id INTEGER NOT NULL,
+	PRIMARY KEY (person_id)
+)`, `
+
+CREATE TABLE version_history (
+	operation VARCHAR(100),
+	dms_version VARCHAR(16)
+)`}
+
+func TestDmsaSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pedsnet/2.2.0/ddl/postgresql/tables/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(sourceTablesSql))
+	}))
+	defer server.Close()
+
+	s := &DmsaSource{Url: server.URL}
+	stmts, err := s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "tables")
+	if err != nil {
+		t.Fatalf("Statements failed: %v", err)
+	}
+	if !reflect.DeepEqual(stmts, sourceTables) {
+		t.Errorf("Unexpected statements: %q", stmts)
+	}
+	if _, err = s.Statements("pedsnet", "2.3.0", "postgresql", "ddl", "tables"); err == nil {
+		t.Error("Expected an error for a missing version")
+	}
+}
+
+func TestFSSource(t *testing.T) {
+	s := &FSSource{FS: fstest.MapFS{
+		"pedsnet/2.2.0/ddl/postgresql/tables.sql": {Data: []byte(sourceTablesSql)},
+	}}
+	stmts, err := s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "tables")
+	if err != nil || !reflect.DeepEqual(stmts, sourceTables) {
+		t.Errorf("Unexpected statements: %q, %v", stmts, err)
+	}
+	if _, err = s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "indexes"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+
+	// The Database applies DDL rules to statements from any source.
+	d := &Database{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: s}
+	if stmts, err = dmsaSql(d, "ddl", "tables", normalPatternsType{table: `CREATE TABLE (\w+)`}); err != nil {
+		t.Fatalf("dmsaSql failed: %v", err)
+	}
+	if len(stmts) != 2 || normalizeSql(stmts[1]) != "CREATE TABLE version_history ( operation VARCHAR(100), dms_version VARCHAR(50) )" {
+		t.Errorf("Unexpected statements: %q", stmts)
+	}
+}
+
+func TestDirSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddlsource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sqlDir := filepath.Join(dir, "pedsnet", "2.2.0", "ddl", "postgresql")
+	if err = os.MkdirAll(sqlDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(sqlDir, "tables.sql"), []byte(sourceTablesSql), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := NewDirSource(dir).Statements("pedsnet", "2.2.0", "postgresql", "ddl", "tables")
+	if err != nil || !reflect.DeepEqual(stmts, sourceTables) {
+		t.Errorf("Unexpected statements: %q, %v", stmts, err)
+	}
+}
+
+func TestDiffDDL(t *testing.T) {
+	server := newDataModelServer()
+	defer server.Close()
+
+	native := &NativeSource{DmUrl: server.URL}
+	indexes, err := native.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "indexes")
+	if err != nil {
+		t.Fatalf("NativeSource failed: %v", err)
+	}
+	bundle := "CREATE INDEX idx_extra ON person (year_of_birth);\n"
+	for _, stmt := range indexes[1:] {
+		bundle += "\n" + stmt + ";\n"
+	}
+	s := &FSSource{FS: fstest.MapFS{"pedsnet/2.2.0/ddl/postgresql/indexes.sql": {Data: []byte(bundle)}}}
+
+	onlyNative, onlyFS, err := DiffDDL(native, s, "pedsnet", "2.2.0", "postgresql", "ddl", "indexes")
+	if err != nil {
+		t.Fatalf("DiffDDL failed: %v", err)
+	}
+	if !reflect.DeepEqual(onlyNative, []string{indexes[0]}) {
+		t.Errorf("Unexpected statements only in native DDL: %q", onlyNative)
+	}
+	if !reflect.DeepEqual(onlyFS, []string{"CREATE INDEX idx_extra ON person (year_of_birth)"}) {
+		t.Errorf("Unexpected statements only in bundled DDL: %q", onlyFS)
+	}
+}
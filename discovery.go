@@ -0,0 +1,188 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ModelInfo describes a data model available from data-models-service, and so from data-models-sqlalchemy, or from an
+// offline DDL bundle.
+type ModelInfo struct {
+	Name     string   `json:"name"`     // Model per https://github.com/chop-dbhi/data-models.
+	Versions []string `json:"versions"` // Available versions, oldest first.
+	Dialects []string `json:"dialects"` // SQL dialects for which DDL is available, e.g. "postgresql".
+}
+
+// Dialects in which data-models-sqlalchemy generates DDL, for every model of data-models-service.
+var dmsaDialects = []string{"mssql", "mysql", "oracle", "postgresql", "sqlite"}
+
+// dmModel is an entry in the list of models returned by data-models-service for /models: one per version of each model.
+type dmModel struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// sortVersions sorts version strings oldest first. Unparseable versions sort last, in lexical order.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		a, errA := ParseModelVersion(versions[i])
+		b, errB := ParseModelVersion(versions[j])
+		if errA != nil || errB != nil {
+			if errA == nil {
+				return true
+			} else if errB == nil {
+				return false
+			}
+			return versions[i] < versions[j]
+		}
+		return a.Less(b)
+	})
+}
+
+// LatestVersion returns the newest of `versions` within `series`, which may be any version range (see ParseVersionRange):
+// e.g. the latest patch release of "2.2", the latest 2.x release for "2", or the latest release overall for "".
+func LatestVersion(versions []string, series string) (string, error) {
+	r, err := ParseVersionRange(series)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		latest        string
+		latestVersion ModelVersion
+	)
+	for _, version := range versions {
+		v, err := ParseModelVersion(version)
+		if err != nil || !r.Contains(v) {
+			continue
+		}
+		if latest == "" || latestVersion.Less(v) {
+			latest, latestVersion = version, v
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("No version in series '%s' among %v", series, versions)
+	}
+	return latest, nil
+}
+
+// ListModels returns the models and versions available from the data-models-service at `dmUrl` (or the default
+// service, if ""), from which data-models-sqlalchemy generates DDL, with the dialects in which it does so.
+func ListModels(dmUrl string) ([]*ModelInfo, error) {
+	if dmUrl == "" {
+		dmUrl = defaultDmUrl
+	}
+	url := joinUrlPath(dmUrl, "/models")
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot access data-models-service at %v: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("Data-models-service (%v) returned error: %v", url, http.StatusText(response.StatusCode))
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading body from %v: %v", url, err)
+	}
+
+	var entries []*dmModel
+	if err = json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("Error parsing model list from %v: %v", url, err)
+	}
+	var models []*ModelInfo
+	byName := make(map[string]*ModelInfo)
+	for _, e := range entries {
+		m := byName[e.Name]
+		if m == nil {
+			m = &ModelInfo{Name: e.Name, Dialects: append([]string(nil), dmsaDialects...)}
+			byName[e.Name] = m
+			models = append(models, m)
+		}
+		m.Versions = append(m.Versions, e.Version)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	for _, m := range models {
+		sortVersions(m.Versions)
+	}
+	return models, nil
+}
+
+// subdirectories returns the sorted names of the directories within `dir`.
+func subdirectories(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+// ListBundleModels returns the models, versions and dialects available in an offline DDL bundle: a directory laid out
+// like the data-models-sqlalchemy URLs, i.e. `{model}/{version}/{ddl|drop}/{dialect}/{tables|indexes|constraints}.sql`.
+func ListBundleModels(dir string) ([]*ModelInfo, error) {
+	modelNames, err := subdirectories(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read DDL bundle `%s`: %v", dir, err)
+	}
+
+	var models []*ModelInfo
+	for _, name := range modelNames {
+		m := &ModelInfo{Name: name}
+		if m.Versions, err = subdirectories(filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+		sortVersions(m.Versions)
+
+		dialects := make(map[string]bool)
+		for _, version := range m.Versions {
+			names, err := subdirectories(filepath.Join(dir, name, version, "ddl"))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			for _, dialect := range names {
+				dialects[dialect] = true
+			}
+		}
+		for dialect := range dialects {
+			m.Dialects = append(m.Dialects, dialect)
+		}
+		sort.Strings(m.Dialects)
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+// ResolveModelVersion returns the latest version of `model` within `series` (e.g. "2.2") available from the
+// data-models-service at `dmUrl` (or the default service, if "").
+func ResolveModelVersion(dmUrl string, model string, series string) (string, error) {
+	models, err := ListModels(dmUrl)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range models {
+		if m.Name == model {
+			return LatestVersion(m.Versions, series)
+		}
+	}
+	if dmUrl == "" {
+		dmUrl = defaultDmUrl
+	}
+	return "", fmt.Errorf("Model '%s' is not available from %s", model, dmUrl)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLatestVersion(t *testing.T) {
+	versions := []string{"2.0.0", "2.1.0", "2.2.0", "2.2.1", "2.10.0", "1.9.9", "bogus"}
+	cases := map[string]string{"2.2": "2.2.1", "2": "2.10.0", "": "2.10.0", "1": "1.9.9", "2.2.0": "2.2.0"}
+	for series, expected := range cases {
+		if latest, err := LatestVersion(versions, series); err != nil || latest != expected {
+			t.Errorf("LatestVersion(%q) = %q, %v; expected %q", series, latest, err, expected)
+		}
+	}
+	if _, err := LatestVersion(versions, "3.0"); err == nil {
+		t.Error("Expected an error for a series with no versions")
+	}
+	if _, err := LatestVersion(versions, "x.y"); err == nil {
+		t.Error("Expected an error for an invalid series")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"name": "pedsnet", "version": "2.2.1", "label": "PEDSnet", "url": "http://example.org/models/pedsnet/2.2.1"},
+			{"name": "omop", "version": "5.0.0", "label": "OMOP", "url": "http://example.org/models/omop/5.0.0"},
+			{"name": "pedsnet", "version": "2.0.0", "label": "PEDSnet", "url": "http://example.org/models/pedsnet/2.0.0"},
+			{"name": "pedsnet", "version": "2.2.0", "label": "PEDSnet", "url": "http://example.org/models/pedsnet/2.2.0"}
+		]`))
+	}))
+	defer server.Close()
+
+	models, err := ListModels(server.URL)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	expected := []*ModelInfo{
+		{Name: "omop", Versions: []string{"5.0.0"}, Dialects: dmsaDialects},
+		{Name: "pedsnet", Versions: []string{"2.0.0", "2.2.0", "2.2.1"}, Dialects: dmsaDialects},
+	}
+	if !reflect.DeepEqual(models, expected) {
+		t.Errorf("Unexpected models: %+v, %+v", models[0], models[1])
+	}
+
+	if version, err := ResolveModelVersion(server.URL, "pedsnet", "2.2"); err != nil || version != "2.2.1" {
+		t.Errorf("ResolveModelVersion returned %q, %v", version, err)
+	}
+	if _, err := ResolveModelVersion(server.URL, "pcornet", "3.0"); err == nil {
+		t.Error("Expected an error for an unknown model")
+	}
+}
+
+func TestListBundleModels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddlbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{"pedsnet/2.2.0/ddl/postgresql", "pedsnet/2.10.0/ddl/postgresql", "pedsnet/2.10.0/ddl/sqlite", "omop/5.0.0/ddl/postgresql"} {
+		if err = os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models, err := ListBundleModels(dir)
+	if err != nil {
+		t.Fatalf("ListBundleModels failed: %v", err)
+	}
+	expected := []*ModelInfo{
+		{Name: "omop", Versions: []string{"5.0.0"}, Dialects: []string{"postgresql"}},
+		{Name: "pedsnet", Versions: []string{"2.2.0", "2.10.0"}, Dialects: []string{"postgresql", "sqlite"}},
+	}
+	if !reflect.DeepEqual(models, expected) {
+		t.Errorf("Unexpected models: %+v, %+v", models[0], models[1])
+	}
+}
@@ -0,0 +1,155 @@
+// Package dmsatest provides a local stand-in for the data-models-sqlalchemy (DMSA) web service, for tests that must run offline.
+//
+// The server serves DDL at the DMSA URLs, `/{model}/{version}/{ddl|drop}/{dialect}/{tables|indexes|constraints}/`,
+// from fixture files laid out as `{model}/{version}/{ddl|drop}/{dialect}/{tables|indexes|constraints}.sql`,
+// the same layout used by offline DDL bundles. Like data-models-service, from which the real service takes its
+// models, it lists the fixture models and versions at `/models`, so it can stand in for both services (e.g. for
+// database.ListModels).
+//
+// Typical use:
+//
+//	server := dmsatest.NewDirServer("test_resources/dmsa")
+//	defer server.Close()
+//	d, err := database.Open("pedsnet", "2.2.0", databaseUrl, "", server.URL, "", "")
+package dmsatest
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake DMSA service. Its methods may be called while requests are being served.
+type Server struct {
+	*httptest.Server
+	FS fs.FS // Fixture files
+
+	mutex    sync.Mutex
+	errors   map[string]int // Injected error status, by request path ("" for every path)
+	latency  time.Duration  // Delay before each response
+	requests []string       // Paths requested, in order
+}
+
+// NewServer starts a server serving the fixture files in `fsys`. Call Close when done.
+func NewServer(fsys fs.FS) *Server {
+	s := &Server{FS: fsys, errors: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewDirServer starts a server serving the fixture files in the directory `dir`. Call Close when done.
+func NewDirServer(dir string) *Server {
+	return NewServer(os.DirFS(dir))
+}
+
+// SetError makes requests for `urlPath` (e.g. "/pedsnet/2.2.0/ddl/postgresql/tables/"), or for every path if "",
+// fail with HTTP status `status`. A status of 0 removes the error.
+func (s *Server) SetError(urlPath string, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if status == 0 {
+		delete(s.errors, urlPath)
+	} else {
+		s.errors[urlPath] = status
+	}
+}
+
+// ClearErrors removes all injected errors.
+func (s *Server) ClearErrors() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.errors = make(map[string]int)
+}
+
+// SetLatency delays every subsequent response by `latency`.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latency = latency
+}
+
+// Requests returns the paths requested so far, in order.
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	latency := s.latency
+	status, failed := s.errors[r.URL.Path]
+	if !failed {
+		status, failed = s.errors[""]
+	}
+	s.mutex.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if failed {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	if r.URL.Path == "/models" || r.URL.Path == "/models/" {
+		s.serveModels(w)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 5 || (parts[2] != "ddl" && parts[2] != "drop") {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := fs.ReadFile(s.FS, path.Join(parts[0], parts[1], parts[2], parts[3], parts[4]+".sql"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(data)
+}
+
+// model is an entry in the list served at /models, as data-models-service lists each version of each model.
+type model struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// serveModels writes the JSON list of the fixture models and versions.
+func (s *Server) serveModels(w http.ResponseWriter) {
+	models := []*model{}
+	names, _ := subdirectories(s.FS, ".")
+	for _, name := range names {
+		versions, _ := subdirectories(s.FS, name)
+		for _, version := range versions {
+			models = append(models, &model{Name: name, Version: version})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models)
+}
+
+// subdirectories returns the sorted names of the directories within `dir` in `fsys`.
+func subdirectories(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,67 @@
+package dmsatest_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/infomodels/database"
+	"github.com/infomodels/database/dmsatest"
+)
+
+func TestServer(t *testing.T) {
+	server := dmsatest.NewDirServer("../test_resources/dmsa")
+	defer server.Close()
+
+	s := &database.DmsaSource{Url: server.URL}
+	stmts, err := s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "indexes")
+	if err != nil {
+		t.Fatalf("Statements failed: %v", err)
+	}
+	if len(stmts) != 6 {
+		t.Errorf("Expected 6 index statements, got %d", len(stmts))
+	}
+	if _, err = s.Statements("pedsnet", "2.2.0", "oracle", "ddl", "indexes"); err == nil {
+		t.Error("Expected an error for a dialect without fixtures")
+	}
+
+	models, err := database.ListModels(server.URL)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "pedsnet" || !reflect.DeepEqual(models[0].Versions, []string{"2.2.0"}) {
+		t.Errorf("Unexpected models: %+v", models)
+	}
+	if version, err := database.ResolveModelVersion(server.URL, "pedsnet", "2.2"); err != nil || version != "2.2.0" {
+		t.Errorf("ResolveModelVersion returned %q, %v", version, err)
+	}
+
+	tablesPath := "/pedsnet/2.2.0/ddl/postgresql/tables/"
+	if requests := server.Requests(); len(requests) != 4 || requests[0] != "/pedsnet/2.2.0/ddl/postgresql/indexes/" {
+		t.Errorf("Unexpected requests: %v", requests)
+	}
+	server.SetError(tablesPath, http.StatusServiceUnavailable)
+	if _, err = s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "tables"); err == nil {
+		t.Error("Expected the injected error")
+	}
+	if _, err = s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "constraints"); err != nil {
+		t.Errorf("Error injected for another path: %v", err)
+	}
+	server.SetError(tablesPath, 0)
+
+	server.SetError("", http.StatusInternalServerError)
+	if _, err = database.ListModels(server.URL); err == nil {
+		t.Error("Expected the injected error for every path")
+	}
+	server.ClearErrors()
+
+	server.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	if _, err = s.Statements("pedsnet", "2.2.0", "postgresql", "ddl", "tables"); err != nil {
+		t.Errorf("Statements failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Response took %v despite the injected latency", elapsed)
+	}
+}
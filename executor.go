@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Executor executes the SQL statements issued by the Database's DDL operations, e.g. CreateTables.
+// The default executes them on the Database's connection, or prints them if there is none.
+type Executor interface {
+	Exec(stmt string) error
+}
+
+// argsExecutor is implemented by Executors that can pass the values of a statement's parameters ($1, $2, ...)
+// separately from the statement.
+type argsExecutor interface {
+	ExecArgs(stmt string, args ...interface{}) error
+}
+
+// dbExecutor executes statements on a database connection.
+type dbExecutor struct {
+	db *sql.DB
+}
+
+func (e *dbExecutor) Exec(stmt string) error {
+	return e.ExecArgs(stmt)
+}
+
+func (e *dbExecutor) ExecArgs(stmt string, args ...interface{}) error {
+	if _, err := e.db.Exec(stmt, args...); err != nil {
+		return fmt.Errorf("Error executing SQL: %v: %v", stmt, err)
+	}
+	return nil
+}
+
+// sqlParameter matches the parameters of a statement.
+var sqlParameter = regexp.MustCompile(`\$(\d+)`)
+
+// inlineArgs returns `stmt` with its parameters ($1, $2, ...) replaced by the values `args` (strings or numbers) as
+// SQL literals, for Executors that cannot pass them separately.
+func inlineArgs(stmt string, args ...interface{}) string {
+	return sqlParameter.ReplaceAllStringFunc(stmt, func(p string) string {
+		i, _ := strconv.Atoi(p[1:])
+		if i < 1 || i > len(args) {
+			return p
+		}
+		if s, ok := args[i-1].(string); ok {
+			return sqlLiteral(s)
+		}
+		return fmt.Sprint(args[i-1])
+	})
+}
+
+// printExecutor prints statements on stdout instead of executing them.
+type printExecutor struct{}
+
+func (e printExecutor) Exec(stmt string) error {
+	fmt.Printf("%s;\n", stmt)
+	return nil
+}
+
+// executor returns the Executor for the Database's statements.
+func (d *Database) executor() Executor {
+	if d.Executor != nil {
+		return d.Executor
+	} else if d.db == nil {
+		return printExecutor{}
+	}
+	return &dbExecutor{d.db}
+}
+
+// RecordingExecutor is an Executor that records statements instead of executing them, and can be told to fail
+// particular statements. It is useful for testing, and for capturing the SQL an operation would execute.
+type RecordingExecutor struct {
+	mutex      sync.Mutex
+	statements []string
+	failures   []recordedFailure
+}
+
+// recordedFailure is an error to be returned for statements containing `match`.
+type recordedFailure struct {
+	match string
+	err   error
+}
+
+// Fail makes subsequent statements containing `match` fail with `err`. Failed statements are still recorded.
+func (e *RecordingExecutor) Fail(match string, err error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.failures = append(e.failures, recordedFailure{match, err})
+}
+
+// Exec implements Executor.
+func (e *RecordingExecutor) Exec(stmt string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.statements = append(e.statements, stmt)
+	for _, f := range e.failures {
+		if strings.Contains(stmt, f.match) {
+			return f.err
+		}
+	}
+	return nil
+}
+
+// Statements returns the statements executed so far, in order.
+func (e *RecordingExecutor) Statements() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.statements...)
+}
+
+// Reset forgets the statements executed so far and any failures.
+func (e *RecordingExecutor) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.statements = nil
+	e.failures = nil
+}
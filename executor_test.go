@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// newRecordingDatabase returns a Database without a connection, whose DDL comes from canned files and whose
+// statements are recorded.
+func newRecordingDatabase() (*Database, *RecordingExecutor) {
+	source := &FSSource{FS: fstest.MapFS{
+		"pedsnet/2.2.0/ddl/postgresql/tables.sql":  {Data: []byte(strings.Join(fixtureTables, ";"))},
+		"pedsnet/2.2.0/ddl/postgresql/indexes.sql": {Data: []byte(strings.Join(upgradeFromIndexes, ";\n"))},
+		"pedsnet/2.2.0/drop/postgresql/indexes.sql": {Data: []byte(
+			"DROP INDEX idx_person_gender;\nDROP INDEX idx_person_source;\nDROP INDEX idx_visit_payer_plan;\n")},
+	}}
+	e := new(RecordingExecutor)
+	return &Database{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: source, Executor: e, driverName: "postgres"}, e
+}
+
+func TestExecutorStatements(t *testing.T) {
+	d, e := newRecordingDatabase()
+	if err := d.CreateIndexes("strict"); err != nil {
+		t.Fatalf("CreateIndexes failed: %v", err)
+	}
+	// Only creating tables, loading and upgrading are recorded in version_history.
+	if statements := e.Statements(); !reflect.DeepEqual(statements, upgradeFromIndexes) {
+		t.Errorf("Unexpected statements:\n%q\nexpected:\n%q", statements, upgradeFromIndexes)
+	}
+
+	// Only the indexes of selected tables are dropped.
+	e.Reset()
+	d.excludeTables = regexp.MustCompile("^visit_payer$")
+	if err := d.DropIndexes("strict"); err != nil {
+		t.Fatalf("DropIndexes failed: %v", err)
+	}
+	if statements := e.Statements(); !reflect.DeepEqual(statements, []string{"DROP INDEX idx_person_gender", "DROP INDEX idx_person_source"}) {
+		t.Errorf("Unexpected statements: %q", statements)
+	}
+}
+
+func TestExecutorErrorModes(t *testing.T) {
+	for _, c := range []struct {
+		errorMode string
+		failure   string
+		fatal     bool
+	}{
+		{"normal", `relation "person" already exists`, false},
+		{"normal", "syntax error", true},
+		{"strict", `relation "person" already exists`, true},
+		{"force", "syntax error", false},
+	} {
+		d, e := newRecordingDatabase()
+		e.Fail("CREATE TABLE person", errors.New(c.failure))
+		err := d.CreateTables(c.errorMode)
+		if (err != nil) != c.fatal {
+			t.Errorf("CreateTables(%q) with error %q returned %v", c.errorMode, c.failure, err)
+		}
+
+		// Statements after a failure are still executed, but the operation is only recorded if it succeeds.
+		statements := e.Statements()
+		if len(statements) < 2 || !strings.Contains(statements[1], "CREATE TABLE visit_payer") {
+			t.Errorf("CreateTables(%q) did not continue after the failure: %q", c.errorMode, statements)
+		}
+		if recorded := strings.HasPrefix(statements[len(statements)-1], "INSERT INTO version_history"); recorded == c.fatal {
+			t.Errorf("CreateTables(%q) with error %q: operation recorded = %v", c.errorMode, c.failure, recorded)
+		}
+	}
+
+	// Failing to record the operation is subject to the error mode too.
+	for errorMode, fatal := range map[string]bool{"force": false, "normal": false, "strict": true} {
+		d, e := newRecordingDatabase()
+		e.Fail("INSERT INTO version_history", errors.New(`relation "version_history" does not exist`))
+		if err := d.CreateTables(errorMode); (err != nil) != fatal {
+			t.Errorf("CreateTables(%q) with version_history missing returned %v", errorMode, err)
+		}
+	}
+}
+
+func TestRecordOperation(t *testing.T) {
+	d, e := newRecordingDatabase()
+	if err := d.RecordOperation("load of O'Brien\\data"); err != nil {
+		t.Fatalf("RecordOperation failed: %v", err)
+	}
+	expected := `INSERT INTO version_history (operation, model, model_version, datetime) VALUES (E'load of O\'Brien\\data', 'pedsnet', '2.2.0', now())`
+	if statements := e.Statements(); !reflect.DeepEqual(statements, []string{expected}) {
+		t.Errorf("Unexpected statements:\n%q\nexpected:\n%q", statements, expected)
+	}
+}
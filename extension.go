@@ -0,0 +1,212 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Extension describes site-specific columns and tables layered on a data model. When a Database has
+// an Extension, its definitions are merged into the DDL used by CreateTables, CreateIndexes,
+// CreateConstraints and the corresponding Drop functions, and the extra tables and columns can be
+// populated by Load like any other.
+//
+// An Extension can be built in Go or loaded from a JSON file with LoadExtension.
+type Extension struct {
+	Columns     []*ExtensionColumn     `json:"columns"`      // Extra columns on data model tables.
+	Tables      []*ExtensionTable      `json:"tables"`       // Extra tables.
+	Indexes     []*ExtensionIndex      `json:"indexes"`      // Indexes on extra tables or columns.
+	ForeignKeys []*ExtensionForeignKey `json:"foreign_keys"` // Foreign keys on extra tables or columns.
+}
+
+// ExtensionColumn describes an extra column.
+type ExtensionColumn struct {
+	Table   string `json:"table"`    // Data model table the column is added to; not needed within an ExtensionTable.
+	Name    string `json:"name"`     // Column name.
+	Type    string `json:"type"`     // SQL type, e.g. "VARCHAR(64)".
+	NotNull bool   `json:"not_null"` // Whether the column is NOT NULL.
+}
+
+// ExtensionTable describes an extra table.
+type ExtensionTable struct {
+	Name       string             `json:"name"`        // Table name.
+	Columns    []*ExtensionColumn `json:"columns"`     // Columns, in order.
+	PrimaryKey []string           `json:"primary_key"` // Primary key columns, if any.
+}
+
+// ExtensionIndex describes an index on an extra table or column.
+type ExtensionIndex struct {
+	Name    string   `json:"name"`    // Index name.
+	Table   string   `json:"table"`   // Table the index is on.
+	Columns []string `json:"columns"` // Indexed columns, in order.
+	Unique  bool     `json:"unique"`  // Whether the index is unique.
+}
+
+// ExtensionForeignKey describes a foreign key constraint on an extra table or column.
+type ExtensionForeignKey struct {
+	Name              string   `json:"name"`               // Constraint name.
+	Table             string   `json:"table"`              // Table the constraint is on.
+	Columns           []string `json:"columns"`            // Referencing columns.
+	ReferencedTable   string   `json:"referenced_table"`   // Referenced table.
+	ReferencedColumns []string `json:"referenced_columns"` // Referenced columns.
+}
+
+// LoadExtension reads an Extension from the JSON file `fileName`.
+func LoadExtension(fileName string) (*Extension, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Extension)
+	if err = json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("Error parsing extension file `%s`: %v", fileName, err)
+	}
+	if err = e.validate(); err != nil {
+		return nil, fmt.Errorf("Error in extension file `%s`: %v", fileName, err)
+	}
+	return e, nil
+}
+
+// validate checks that the extension's definitions are complete.
+func (e *Extension) validate() error {
+	checkColumn := func(c *ExtensionColumn, table string) error {
+		if c.Name == "" || c.Type == "" {
+			return fmt.Errorf("Extension column on table `%s` requires a name and type", table)
+		}
+		return nil
+	}
+	for _, c := range e.Columns {
+		if c.Table == "" {
+			return fmt.Errorf("Extension column `%s` requires a table", c.Name)
+		}
+		if err := checkColumn(c, c.Table); err != nil {
+			return err
+		}
+	}
+	for _, t := range e.Tables {
+		if t.Name == "" || len(t.Columns) == 0 {
+			return fmt.Errorf("Extension tables require a name and at least one column")
+		}
+		for _, c := range t.Columns {
+			if err := checkColumn(c, t.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, i := range e.Indexes {
+		if i.Name == "" || i.Table == "" || len(i.Columns) == 0 {
+			return fmt.Errorf("Extension indexes require a name, table and at least one column")
+		}
+	}
+	for _, fk := range e.ForeignKeys {
+		if fk.Name == "" || fk.Table == "" || fk.ReferencedTable == "" || len(fk.Columns) == 0 || len(fk.Columns) != len(fk.ReferencedColumns) {
+			return fmt.Errorf("Extension foreign key `%s` requires a name, table, referenced table and matching column lists", fk.Name)
+		}
+	}
+	return nil
+}
+
+// columnSql returns the SQL definition of an extension column.
+func (c *ExtensionColumn) columnSql() string {
+	return (&columnDefinition{name: c.Name, sqlType: c.Type, notNull: c.NotNull}).definition()
+}
+
+// createSql returns the CREATE TABLE statement for an extension table.
+func (t *ExtensionTable) createSql() string {
+	var parts []string
+	for _, c := range t.Columns {
+		parts = append(parts, c.columnSql())
+	}
+	if len(t.PrimaryKey) > 0 {
+		parts = append(parts, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(t.PrimaryKey, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", t.Name, strings.Join(parts, ", \n\t"))
+}
+
+// createSql returns the CREATE INDEX statement for an extension index.
+func (i *ExtensionIndex) createSql() string {
+	unique := ""
+	if i.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, i.Name, i.Table, strings.Join(i.Columns, ", "))
+}
+
+// createSql returns the ALTER TABLE statement adding an extension foreign key.
+func (fk *ExtensionForeignKey) createSql() string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY(%s) REFERENCES %s (%s)", fk.Table, fk.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", "))
+}
+
+// addColumnsToCreateTable inserts column definitions before the closing parenthesis of a CREATE TABLE statement.
+func addColumnsToCreateTable(stmt string, columns []*ExtensionColumn) string {
+	end := strings.LastIndex(stmt, ")")
+	if end < 0 {
+		return stmt
+	}
+	head := strings.TrimRight(stmt[:end], " \t\r\n")
+	for _, c := range columns {
+		head += ", \n\t" + c.columnSql()
+	}
+	return head + "\n" + stmt[end:]
+}
+
+// apply merges the extension into the DDL statements `stmts` for the given operator and operand.
+//
+// Creation statements for extension tables, indexes and constraints are appended, so they follow the
+// data model objects they may refer to; drop statements are prepended for the same reason.
+func (e *Extension) apply(stmts []string, ddlOperator string, ddlOperand string) ([]string, error) {
+	var extra []string
+
+	switch ddlOperator + "-" + ddlOperand {
+	case "ddl-tables":
+		columns := make(map[string][]*ExtensionColumn)
+		for _, c := range e.Columns {
+			columns[c.Table] = append(columns[c.Table], c)
+		}
+		result := make([]string, 0, len(stmts))
+		for _, stmt := range stmts {
+			if t, _ := parseTableDefinition(stmt); t != nil && columns[t.name] != nil {
+				stmt = addColumnsToCreateTable(stmt, columns[t.name])
+				delete(columns, t.name)
+			}
+			result = append(result, stmt)
+		}
+		if len(columns) > 0 {
+			var tables []string
+			for table := range columns {
+				tables = append(tables, table)
+			}
+			sort.Strings(tables)
+			return nil, fmt.Errorf("Extension adds columns to tables not in the data model: %s", strings.Join(tables, ", "))
+		}
+		for _, t := range e.Tables {
+			result = append(result, t.createSql())
+		}
+		return result, nil
+	case "ddl-indexes":
+		for _, i := range e.Indexes {
+			extra = append(extra, i.createSql())
+		}
+		return append(stmts, extra...), nil
+	case "ddl-constraints":
+		for _, fk := range e.ForeignKeys {
+			extra = append(extra, fk.createSql())
+		}
+		return append(stmts, extra...), nil
+	case "drop-tables":
+		for _, t := range e.Tables {
+			extra = append(extra, fmt.Sprintf("DROP TABLE %s", t.Name))
+		}
+	case "drop-indexes":
+		for _, i := range e.Indexes {
+			extra = append(extra, fmt.Sprintf("DROP INDEX %s", i.Name))
+		}
+	case "drop-constraints":
+		for _, fk := range e.ForeignKeys {
+			extra = append(extra, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", fk.Table, fk.Name))
+		}
+	}
+	return append(extra, stmts...), nil
+}
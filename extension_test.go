@@ -0,0 +1,70 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testExtension = &Extension{
+	Columns: []*ExtensionColumn{
+		{Table: "person", Name: "site_person_id", Type: "VARCHAR(64)"},
+	},
+	Tables: []*ExtensionTable{
+		{
+			Name: "site_person_map",
+			Columns: []*ExtensionColumn{
+				{Name: "person_id", Type: "INTEGER", NotNull: true},
+				{Name: "local_mrn", Type: "VARCHAR(32)", NotNull: true},
+			},
+			PrimaryKey: []string{"person_id", "local_mrn"},
+		},
+	},
+	Indexes: []*ExtensionIndex{
+		{Name: "idx_site_person_id", Table: "person", Columns: []string{"site_person_id"}},
+	},
+	ForeignKeys: []*ExtensionForeignKey{
+		{Name: "fk_site_person_map_person", Table: "site_person_map", Columns: []string{"person_id"}, ReferencedTable: "person", ReferencedColumns: []string{"person_id"}},
+	},
+}
+
+func TestExtensionTables(t *testing.T) {
+	stmts := []string{"\nCREATE TABLE person (\n\tperson_id INTEGER NOT NULL, \n\tPRIMARY KEY (person_id)\n)\n\n"}
+	result, err := testExtension.apply(stmts, "ddl", "tables")
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	tables, err := parseTableDefinitions(result)
+	if err != nil {
+		t.Fatalf("parseTableDefinitions failed: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(tables))
+	}
+	if c := tables["person"].column("site_person_id"); c == nil || c.sqlType != "VARCHAR(64)" || c.notNull {
+		t.Errorf("Extension column not merged correctly: %q", result[0])
+	}
+	if !reflect.DeepEqual(tables["person"].constraints, []string{"PRIMARY KEY (person_id)"}) {
+		t.Errorf("Primary key damaged by merge: %q", result[0])
+	}
+	if m := tables["site_person_map"]; m == nil || len(m.columns) != 2 || !m.columns[1].notNull {
+		t.Errorf("Extension table not created correctly: %q", result[1])
+	}
+
+	if _, err = testExtension.apply([]string{"CREATE TABLE death (person_id INTEGER)"}, "ddl", "tables"); err == nil {
+		t.Error("Expected an error for a column on a table not in the model")
+	}
+}
+
+func TestExtensionIndexesAndConstraints(t *testing.T) {
+	result, _ := testExtension.apply([]string{"CREATE INDEX idx_person ON person (person_id)"}, "ddl", "indexes")
+	expected := []string{"CREATE INDEX idx_person ON person (person_id)", "CREATE INDEX idx_site_person_id ON person (site_person_id)"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected indexes: %q", result)
+	}
+
+	result, _ = testExtension.apply([]string{"ALTER TABLE death DROP CONSTRAINT fpk_death_person"}, "drop", "constraints")
+	expected = []string{"ALTER TABLE site_person_map DROP CONSTRAINT fk_site_person_map_person", "ALTER TABLE death DROP CONSTRAINT fpk_death_person"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected constraint drops: %q", result)
+	}
+}
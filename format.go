@@ -0,0 +1,587 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// FileFormat describes how a delimited data file is written. The zero value is CSV with a header, as PostgreSQL's
+// COPY reads it by default: comma-delimited, double-quoted, empty unquoted fields NULL, UTF-8.
+type FileFormat struct {
+	Delimiter  string   // Field delimiter, a single character; "" for ",".
+	Quote      string   // Quote character; "" for `"`.
+	Escape     string   // Character escaping the quote character within quoted fields; "" for the quote character (i.e. doubled quotes).
+	NoQuoting  bool     // Fields are never quoted, so quote characters are data (e.g. Athena vocabulary files).
+	Null       string   // Text of an unquoted NULL field; "" for an empty field.
+	NoHeader   bool     // The data has no header row.
+	Columns    []string // Columns of data without a header; nil for all columns of the table, in order.
+	Encoding   string   // Character encoding, as named by PostgreSQL, e.g. "LATIN1"; "" for UTF-8.
+	DateFormat string   // Layout of date values (see dateStyles); "" for YYYY-MM-DD.
+
+	NullPolicy         NullPolicy            // Which fields are NULL (see NullPolicy); "" for those equal to Null.
+	ColumnNullPolicies map[string]NullPolicy // Policies of particular columns, overriding NullPolicy.
+
+	HeaderMap map[string]string // Columns of headers that are not their names, keyed by normalized header (see normalizeHeader).
+
+	exactNull bool // Only unquoted fields equal to Null are NULL, whatever the NULL policies (see convertedFormat).
+}
+
+// NullPolicy says which fields of a column are loaded as NULL: NullIfEmpty, EmptyString or, for any other value, the
+// fields equal to that token, e.g. `\N` or "NULL", with empty fields loaded as empty strings. A column's policy
+// overrides the file's. COPY reads a file with a single null string, so a file whose columns have different tokens, or
+// a token and NullIfEmpty, is converted as it is loaded (see needsNullConversion). Parquet and JSON Lines data have
+// NULLs of their own, which are always NULL, and only a policy that is set makes other values NULL too (see
+// isConvertedNull).
+type NullPolicy string
+
+const (
+	NullIfEmpty NullPolicy = "empty-is-null"  // Empty fields, quoted or not, are NULL.
+	EmptyString NullPolicy = "empty-is-empty" // Empty fields are empty strings, and no field is NULL.
+)
+
+// Named file formats, for the "format" field of a manifest.
+var FileFormats = map[string]FileFormat{
+	"csv":  {},
+	"tsv":  {Delimiter: "\t"},
+	"pipe": {Delimiter: "|"},
+	// OHDSI Athena vocabulary downloads: tab-delimited, unquoted, with YYYYMMDD dates.
+	"athena": {Delimiter: "\t", NoQuoting: true, DateFormat: "YYYYMMDD"},
+}
+
+// PostgreSQL DateStyle settings for reading dates in each supported DateFormat layout, or "" if PostgreSQL reads the
+// layout whatever the DateStyle.
+var dateStyles = map[string]string{
+	"":           "",
+	"YYYY-MM-DD": "",
+	"YYYYMMDD":   "",
+	"MM/DD/YYYY": "ISO, MDY",
+	"DD/MM/YYYY": "ISO, DMY",
+}
+
+var encodingPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Characters that may be given by name in a manifest, where they are awkward to write literally.
+var formatCharacterNames = map[string]string{`\t`: "\t", "tab": "\t", "comma": ",", "pipe": "|", "semicolon": ";"}
+
+// fileFormat returns the format of the file of the manifest entry `m`: the Database's default format (see
+// LoadOptions), or the named format in the entry's "format" field, with any of the entry's "delimiter", "quote",
+// "escape", "null", "header", "encoding" and "date_format" fields applied. A headerless file is taken to have all
+// columns of its table, in order, from `tables`, unless the format gives its columns.
+//
+// The NULL policies of the file's table and its columns in d.LoadOptions.NullPolicies apply, unless the entry has a
+// "null" field, and those in the entry's "null_policy" field apply over them. The field holds a policy for the table
+// and/or policies for columns, separated by semicolons, e.g. "empty-is-null;source_value=empty-is-empty". Likewise,
+// the headers of the table in d.LoadOptions.HeaderMap are mapped to their columns, as are those of the entry's
+// "header_map" field, e.g. "PatientID=person_id;DOB=birth_date".
+func (d *Database) fileFormat(m map[string]string, tables map[string]*tableDefinition) (FileFormat, error) {
+	format := d.LoadOptions.Format
+	if name := m["format"]; name != "" {
+		var ok bool
+		if format, ok = FileFormats[strings.ToLower(name)]; !ok {
+			return format, fmt.Errorf("unknown format '%s'", name)
+		}
+	}
+
+	columnPolicies := make(map[string]NullPolicy)
+	for column, policy := range format.ColumnNullPolicies {
+		columnPolicies[column] = policy
+	}
+	if m["null"] == "" {
+		for key, policy := range d.LoadOptions.NullPolicies {
+			if key == m["table"] {
+				format.NullPolicy = policy
+			} else if strings.HasPrefix(key, m["table"]+".") {
+				columnPolicies[strings.TrimPrefix(key, m["table"]+".")] = policy
+			}
+		}
+	} else {
+		format.NullPolicy = ""
+	}
+	if m["null_policy"] != "" {
+		for _, policy := range strings.Split(m["null_policy"], ";") {
+			policy = strings.TrimSpace(policy)
+			if i := strings.Index(policy, "="); i >= 0 {
+				columnPolicies[strings.TrimSpace(policy[:i])] = NullPolicy(strings.TrimSpace(policy[i+1:]))
+			} else {
+				format.NullPolicy = NullPolicy(policy)
+			}
+		}
+	}
+	format.ColumnNullPolicies = nil
+	if len(columnPolicies) > 0 {
+		format.ColumnNullPolicies = columnPolicies
+	}
+
+	headerMap := make(map[string]string)
+	for header, column := range format.HeaderMap {
+		headerMap[normalizeHeader(header)] = column
+	}
+	for _, tablePrefix := range []bool{false, true} {
+		for key, column := range d.LoadOptions.HeaderMap {
+			if strings.HasPrefix(key, m["table"]+".") == tablePrefix {
+				headerMap[normalizeHeader(strings.TrimPrefix(key, m["table"]+"."))] = strings.TrimSpace(column)
+			}
+		}
+	}
+	if m["header_map"] != "" {
+		for _, mapping := range strings.Split(m["header_map"], ";") {
+			i := strings.Index(mapping, "=")
+			if i < 0 {
+				return format, fmt.Errorf("invalid header mapping '%s'; expected header=column", strings.TrimSpace(mapping))
+			}
+			headerMap[normalizeHeader(mapping[:i])] = strings.TrimSpace(mapping[i+1:])
+		}
+	}
+	format.HeaderMap = nil
+	if len(headerMap) > 0 {
+		format.HeaderMap = headerMap
+	}
+
+	for field, value := range map[string]*string{"delimiter": &format.Delimiter, "quote": &format.Quote, "escape": &format.Escape} {
+		if m[field] != "" {
+			*value = m[field]
+			if c, ok := formatCharacterNames[strings.ToLower(m[field])]; ok {
+				*value = c
+			}
+		}
+	}
+	if m["null"] != "" {
+		format.Null = m["null"]
+	}
+	if m["encoding"] != "" {
+		format.Encoding = m["encoding"]
+	}
+	if m["date_format"] != "" {
+		format.DateFormat = m["date_format"]
+	}
+	switch strings.ToLower(m["header"]) {
+	case "":
+	case "true", "yes", "1":
+		format.NoHeader = false
+	case "false", "no", "0":
+		format.NoHeader = true
+	default:
+		return format, fmt.Errorf("invalid header '%s'", m["header"])
+	}
+
+	if err := format.validate(); err != nil {
+		return format, err
+	}
+	if format.NoHeader && format.Columns == nil {
+		if t := tables[m["table"]]; t != nil {
+			for _, c := range t.columns {
+				format.Columns = append(format.Columns, c.name)
+			}
+		}
+	}
+	return format, nil
+}
+
+// headerColumns returns the columns named by the fields of a `header` record: each normalized (see normalizeHeader),
+// or mapped to a column by the format's HeaderMap.
+func (f FileFormat) headerColumns(header []string) []string {
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = normalizeHeader(name)
+		if column, ok := f.HeaderMap[columns[i]]; ok {
+			columns[i] = column
+		}
+	}
+	return columns
+}
+
+// validate checks that the format can be read, by PostgreSQL and by this package.
+func (f FileFormat) validate() error {
+	for name, value := range map[string]string{"delimiter": f.Delimiter, "quote": f.Quote, "escape": f.Escape} {
+		if value != "" && (len(value) != 1 || value[0] >= 0x80 || value == "\n" || value == "\r") {
+			return fmt.Errorf("%s must be a single ASCII character other than newline, not '%s'", name, value)
+		}
+	}
+	if !f.NoQuoting && f.delimiter() == f.quote() {
+		return fmt.Errorf("delimiter and quote must differ")
+	}
+	if strings.ContainsAny(f.Null, "\r\n") || (f.Delimiter != "" && strings.Contains(f.Null, f.Delimiter)) {
+		return fmt.Errorf("null marker must not contain a newline or the delimiter")
+	}
+	if err := f.validateNullPolicies(); err != nil {
+		return err
+	}
+	if f.Encoding != "" && !encodingPattern.MatchString(f.Encoding) {
+		return fmt.Errorf("invalid encoding '%s'", f.Encoding)
+	}
+	if _, ok := dateStyles[strings.ToUpper(f.DateFormat)]; !ok {
+		return fmt.Errorf("unsupported date format '%s'", f.DateFormat)
+	}
+	return nil
+}
+
+func (f FileFormat) delimiter() byte {
+	if f.Delimiter == "" {
+		return ','
+	}
+	return f.Delimiter[0]
+}
+
+func (f FileFormat) quote() byte {
+	if f.Quote == "" {
+		return '"'
+	}
+	return f.Quote[0]
+}
+
+func (f FileFormat) escape() byte {
+	if f.Escape == "" {
+		return f.quote()
+	}
+	return f.Escape[0]
+}
+
+// validateNullPolicies checks that the format's NULL policies are known and that their null tokens can be read.
+func (f FileFormat) validateNullPolicies() error {
+	for _, policy := range f.nullPolicies() {
+		switch {
+		case policy == NullIfEmpty || policy == EmptyString || policy == "":
+		case strings.HasPrefix(strings.ToLower(string(policy)), "empty-"):
+			return fmt.Errorf("unknown NULL policy '%s'", policy)
+		case strings.ContainsAny(string(policy), "\r\n") || strings.IndexByte(string(policy), f.delimiter()) >= 0:
+			return fmt.Errorf("null token must not contain a newline or the delimiter")
+		}
+	}
+	return nil
+}
+
+// nullPolicies returns the NULL policies of the format's columns: the default policy, then those of particular columns.
+func (f FileFormat) nullPolicies() []NullPolicy {
+	policies := []NullPolicy{f.defaultNullPolicy()}
+	for _, policy := range f.ColumnNullPolicies {
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// needsNullConversion reports whether COPY, which reads a file with a single null string, cannot apply the format's
+// NULL policies: if they have different null tokens, or a null token and NullIfEmpty. Such data is converted to
+// nullConvertedFormat as it is loaded (see convertNulls).
+func (f FileFormat) needsNullConversion() bool {
+	var token NullPolicy
+	emptyIsNull := false
+	for _, policy := range f.nullPolicies() {
+		switch policy {
+		case NullIfEmpty:
+			emptyIsNull = true
+		case EmptyString, "":
+		default:
+			if token != "" && policy != token {
+				return true
+			}
+			token = policy
+		}
+	}
+	return token != "" && emptyIsNull
+}
+
+// nullConvertedFormat returns the format of data in `format` converted by convertNulls.
+func nullConvertedFormat(format FileFormat) FileFormat {
+	return FileFormat{Null: convertedFormat.Null, Encoding: format.Encoding, DateFormat: format.DateFormat, exactNull: true}
+}
+
+// convertNulls copies the records of the data read from `r`, in `format` with columns `columns`, to `w` as CSV with a
+// header, in nullConvertedFormat, writing each field that `format`'s NULL policies make NULL as NULL.
+func convertNulls(w io.Writer, r io.Reader, columns []string, format FileFormat) error {
+	csvWriter := newConvertedCsvWriter(w)
+	if err := csvWriter.write(columns, nil); err != nil {
+		return err
+	}
+	recordReader := format.newRecordReader(r)
+	if !format.NoHeader {
+		if _, err := recordReader.read(false); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	for {
+		record, err := recordReader.read(true)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("line %d: %v", recordReader.line, err)
+		}
+		nulls := make([]bool, len(record))
+		for i := range record {
+			if i < len(columns) {
+				nulls[i] = format.isNull(columns[i], record[i])
+			}
+		}
+		if err = csvWriter.write(record, nulls); err != nil {
+			return err
+		}
+	}
+	return csvWriter.flush()
+}
+
+// defaultNullPolicy returns the NULL policy of columns without their own.
+func (f FileFormat) defaultNullPolicy() NullPolicy {
+	switch {
+	case f.NullPolicy != "":
+		return f.NullPolicy
+	case f.Null != "":
+		return NullPolicy(f.Null)
+	}
+	return NullIfEmpty
+}
+
+// columnNullPolicy returns the NULL policy of `column`.
+func (f FileFormat) columnNullPolicy(column string) NullPolicy {
+	if policy := f.ColumnNullPolicies[column]; policy != "" {
+		return policy
+	}
+	return f.defaultNullPolicy()
+}
+
+// nullString returns the null string of COPY: the null token of the format's NULL policies, if any, or "".
+func (f FileFormat) nullString() string {
+	for _, policy := range f.nullPolicies() {
+		if policy != NullIfEmpty && policy != EmptyString && policy != "" {
+			return string(policy)
+		}
+	}
+	return ""
+}
+
+// isNull reports whether the field `value` of `column` is loaded as NULL.
+func (f FileFormat) isNull(column string, value string) bool {
+	switch policy := f.columnNullPolicy(column); policy {
+	case EmptyString:
+		return false
+	case NullIfEmpty:
+		return value == ""
+	default:
+		return value == string(policy)
+	}
+}
+
+// isConvertedNull reports whether the value `value` of `column` in Parquet or JSON Lines data, which is not NULL
+// there, is loaded as NULL: an empty string if the column's policy is NullIfEmpty, or the policy's token.
+func (f FileFormat) isConvertedNull(column string, value string) bool {
+	policy := f.ColumnNullPolicies[column]
+	if policy == "" {
+		policy = f.NullPolicy
+	}
+	switch policy {
+	case "", EmptyString:
+		return false
+	case NullIfEmpty:
+		return value == ""
+	}
+	return value == string(policy)
+}
+
+// dateStyle returns the PostgreSQL DateStyle needed to read the format's dates, or "" if the default will do.
+func (f FileFormat) dateStyle() string {
+	return dateStyles[strings.ToUpper(f.DateFormat)]
+}
+
+// copyOptions returns the options of a COPY command reading data in the format into `columns`. Fields are matched
+// against the null string even when quoted (FORCE_NULL), except in columns whose NULL policy is EmptyString, which
+// are never NULL (FORCE_NOT_NULL), and in formats with exactNull.
+func (f FileFormat) copyOptions(columns []string) string {
+	options := []string{"FORMAT csv", fmt.Sprintf("HEADER %t", !f.NoHeader)}
+	if f.Delimiter != "" {
+		options = append(options, "DELIMITER "+sqlLiteral(f.Delimiter))
+	}
+	if f.NoQuoting {
+		// COPY's csv format always has a quote character, so use one that does not occur in text.
+		options = append(options, `QUOTE E'\b'`)
+	} else {
+		if f.Quote != "" {
+			options = append(options, "QUOTE "+sqlLiteral(f.Quote))
+		}
+		if f.Escape != "" {
+			options = append(options, "ESCAPE "+sqlLiteral(f.Escape))
+		}
+	}
+	if null := f.nullString(); null != "" {
+		options = append(options, "NULL "+sqlLiteral(null))
+	}
+	encoding := "utf-8"
+	if f.Encoding != "" {
+		encoding = f.Encoding
+	}
+	options = append(options, fmt.Sprintf("ENCODING '%s'", encoding))
+
+	if f.exactNull {
+		return strings.Join(options, ", ")
+	}
+	var forceNull, forceNotNull []string
+	for _, column := range columns {
+		if f.columnNullPolicy(column) == EmptyString {
+			forceNotNull = append(forceNotNull, column)
+		} else {
+			forceNull = append(forceNull, column)
+		}
+	}
+	if len(forceNull) > 0 {
+		options = append(options, fmt.Sprintf("FORCE_NULL(%s)", strings.Join(forceNull, ", ")))
+	}
+	if len(forceNotNull) > 0 {
+		options = append(options, fmt.Sprintf("FORCE_NOT_NULL(%s)", strings.Join(forceNotNull, ", ")))
+	}
+	return strings.Join(options, ", ")
+}
+
+// sqlLiteral returns `s` as a SQL string literal, using PostgreSQL's escape string syntax if it contains a backslash
+// or control character.
+func sqlLiteral(s string) string {
+	if !strings.ContainsAny(s, "\\\t\n\r\b\f") {
+		return "'" + strings.Replace(s, "'", "''", -1) + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, "'", `\'`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\b", `\b`, "\f", `\f`)
+	return "E'" + r.Replace(s) + "'"
+}
+
+// convertedFormat is the format of the CSV to which Parquet and JSON Lines data is converted for COPY, in which NULLs
+// are written as an unquoted `\N` (see convertedCsvWriter), so that they are distinct from empty strings.
+var convertedFormat = FileFormat{Null: `\N`, exactNull: true}
+
+// convertedCsvWriter writes records as CSV in convertedFormat.
+type convertedCsvWriter struct {
+	w *bufio.Writer
+}
+
+func newConvertedCsvWriter(w io.Writer) *convertedCsvWriter {
+	return &convertedCsvWriter{bufio.NewWriter(w)}
+}
+
+// write writes `record`, whose fields are NULL where `nulls` is true. Other fields are quoted if they are empty or
+// might otherwise be read as something else, e.g. `\N`.
+func (cw *convertedCsvWriter) write(record []string, nulls []bool) error {
+	for i, field := range record {
+		if i > 0 {
+			cw.w.WriteByte(',')
+		}
+		switch {
+		case nulls != nil && nulls[i]:
+			cw.w.WriteString(convertedFormat.Null)
+		case field == "" || strings.ContainsAny(field, ",\"\r\n\\") || field[0] == ' ' || field[0] == '\t':
+			cw.w.WriteString(`"` + strings.Replace(field, `"`, `""`, -1) + `"`)
+		default:
+			cw.w.WriteString(field)
+		}
+	}
+	_, err := cw.w.WriteString("\n")
+	return err
+}
+
+// flush writes any buffered data to the underlying io.Writer.
+func (cw *convertedCsvWriter) flush() error {
+	return cw.w.Flush()
+}
+
+// recordReader reads the records of data in a FileFormat, splitting them into fields as PostgreSQL's COPY does.
+// Unlike encoding/csv, it supports any quote and escape characters and unquoted data.
+type recordReader struct {
+	r         *bufio.Reader
+	delimiter byte
+	quote     byte
+	escape    byte
+	quoting   bool
+	field     []byte
+	lines     int // Number of lines read
+	line      int // Line on which the last record read began
+}
+
+// newRecordReader returns a recordReader of the data in `r`, in the format.
+func (f FileFormat) newRecordReader(r io.Reader) *recordReader {
+	return &recordReader{r: bufio.NewReader(r), delimiter: f.delimiter(), quote: f.quote(), escape: f.escape(), quoting: !f.NoQuoting}
+}
+
+// read returns the fields of the next record, or io.EOF at the end of the data. Empty lines are skipped. If `keep`
+// is false, the record is skipped without collecting its fields.
+func (rr *recordReader) read(keep bool) ([]string, error) {
+	for {
+		fields, empty, err := rr.readLine(keep)
+		if err != nil || !empty {
+			return fields, err
+		}
+	}
+}
+
+// readLine reads a record, reporting whether it was an empty line.
+func (rr *recordReader) readLine(keep bool) (fields []string, empty bool, err error) {
+	var (
+		inQuotes  bool
+		started   bool
+		delimited bool // The record has a delimiter or quote, so is not empty
+	)
+	rr.field = rr.field[:0]
+	rr.line = rr.lines + 1
+	endField := func() {
+		if keep {
+			fields = append(fields, string(rr.field))
+		}
+		rr.field = rr.field[:0]
+	}
+
+	for {
+		b, err := rr.r.ReadByte()
+		if err == io.EOF {
+			if inQuotes {
+				return nil, false, fmt.Errorf("unterminated quoted field at end of data")
+			}
+			if !started {
+				return nil, false, io.EOF
+			}
+			break
+		} else if err != nil {
+			return nil, false, err
+		}
+		if b == '\n' {
+			rr.lines++
+		}
+
+		switch {
+		case inQuotes && b == rr.escape && rr.escape != rr.quote:
+			next, err := rr.r.ReadByte()
+			if err == nil && (next == rr.quote || next == rr.escape) {
+				b = next
+			} else if err == nil {
+				rr.r.UnreadByte()
+			}
+			rr.field = append(rr.field, b)
+		case inQuotes && b == rr.quote:
+			if rr.escape == rr.quote {
+				if next, err := rr.r.ReadByte(); err == nil && next == rr.quote {
+					rr.field = append(rr.field, b)
+					break
+				} else if err == nil {
+					rr.r.UnreadByte()
+				}
+			}
+			inQuotes = false
+		case inQuotes:
+			rr.field = append(rr.field, b)
+		case rr.quoting && b == rr.quote:
+			inQuotes, delimited = true, true
+		case b == rr.delimiter:
+			endField()
+			delimited = true
+		case b == '\n':
+			if n := len(rr.field); n > 0 && rr.field[n-1] == '\r' {
+				rr.field = rr.field[:n-1]
+			}
+			if !delimited && len(rr.field) == 0 {
+				return nil, true, nil
+			}
+			endField()
+			return fields, false, nil
+		default:
+			rr.field = append(rr.field, b)
+		}
+		started = true
+	}
+	endField()
+	return fields, false, nil
+}
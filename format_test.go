@@ -0,0 +1,187 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readRecords returns all records of `data` in `format`.
+func readRecords(t *testing.T, data string, format FileFormat) [][]string {
+	var records [][]string
+	rr := format.newRecordReader(strings.NewReader(data))
+	for {
+		record, err := rr.read(true)
+		if err == io.EOF {
+			return records
+		} else if err != nil {
+			t.Fatalf("Error reading %q: %v", data, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	tests := []struct {
+		data     string
+		format   FileFormat
+		expected [][]string
+	}{
+		{"a,b\r\n1,\"x,\"\"y\"\"\nz\"\n\n2,\n", FileFormat{}, [][]string{{"a", "b"}, {"1", "x,\"y\"\nz"}, {"2", ""}}},
+		{"a\tb\n1\t\"quoted\"\n", FileFormats["athena"], [][]string{{"a", "b"}, {"1", "\"quoted\""}}},
+		{"a|b\n'x|y'|\\\n", FileFormat{Delimiter: "|", Quote: "'", Escape: "\\"}, [][]string{{"a", "b"}, {"x|y", "\\"}}},
+		{"a|b\n'it\\'s'|z", FileFormat{Delimiter: "|", Quote: "'", Escape: "\\"}, [][]string{{"a", "b"}, {"it's", "z"}}},
+		{",\n", FileFormat{}, [][]string{{"", ""}}},
+	}
+	for _, test := range tests {
+		if records := readRecords(t, test.data, test.format); !reflect.DeepEqual(records, test.expected) {
+			t.Errorf("Read %q as %q; expected %q", test.data, records, test.expected)
+		}
+	}
+
+	if count, err := csvRecordCount(strings.NewReader("1\t2\n3\t4\n"), FileFormat{Delimiter: "\t", NoHeader: true}); err != nil || count != 2 {
+		t.Errorf("Counted %d headerless records, %v; expected 2", count, err)
+	}
+	if _, err := csvRecordCount(strings.NewReader("a\n\"open\n"), FileFormat{}); err == nil {
+		t.Error("Expected an error for an unterminated quoted field")
+	}
+}
+
+func TestCopyOptions(t *testing.T) {
+	tests := map[string]FileFormat{
+		"FORMAT csv, HEADER true, ENCODING 'utf-8', FORCE_NULL(a, b)":                                                         {},
+		`FORMAT csv, HEADER true, DELIMITER E'\t', QUOTE E'\b', ENCODING 'utf-8', FORCE_NULL(a, b)`:                           FileFormats["athena"],
+		`FORMAT csv, HEADER false, DELIMITER '|', QUOTE '''', ESCAPE E'\\', NULL 'NULL', ENCODING 'LATIN1', FORCE_NULL(a, b)`: {Delimiter: "|", Quote: "'", Escape: "\\", Null: "NULL", NoHeader: true, Encoding: "LATIN1"},
+		"FORMAT csv, HEADER true, ENCODING 'utf-8', FORCE_NOT_NULL(a, b)":                                                     {NullPolicy: EmptyString},
+		`FORMAT csv, HEADER true, NULL E'\\N', ENCODING 'utf-8', FORCE_NULL(a), FORCE_NOT_NULL(b)`:                            {NullPolicy: `\N`, ColumnNullPolicies: map[string]NullPolicy{"b": EmptyString}},
+		"FORMAT csv, HEADER true, ENCODING 'utf-8', FORCE_NULL(b), FORCE_NOT_NULL(a)":                                         {Null: "NULL", NullPolicy: EmptyString, ColumnNullPolicies: map[string]NullPolicy{"b": NullIfEmpty}},
+	}
+	for expected, format := range tests {
+		if options := format.copyOptions([]string{"a", "b"}); options != expected {
+			t.Errorf("Unexpected COPY options %s; expected %s", options, expected)
+		}
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	tables, err := parseTableDefinitions(fixtureTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Database{LoadOptions: LoadOptions{Format: FileFormats["tsv"]}}
+
+	format, err := d.fileFormat(map[string]string{"table": "person"}, tables)
+	if err != nil || !reflect.DeepEqual(format, FileFormats["tsv"]) {
+		t.Errorf("Expected the default format, got %+v, %v", format, err)
+	}
+
+	format, err = d.fileFormat(map[string]string{"table": "person", "format": "Athena", "null": `\N`, "header": "false"}, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Delimiter != "\t" || !format.NoQuoting || format.Null != `\N` || !format.NoHeader || format.DateFormat != "YYYYMMDD" {
+		t.Errorf("Unexpected format %+v", format)
+	}
+	if !reflect.DeepEqual(format.Columns, []string{"person_id", "gender_concept_id", "year_of_birth", "person_source_value", "pn_gestational_age"}) {
+		t.Errorf("Expected the columns of person for a headerless file, got %v", format.Columns)
+	}
+
+	format, err = d.fileFormat(map[string]string{"delimiter": "pipe", "quote": "'", "date_format": "dd/mm/yyyy"}, tables)
+	if err != nil || format.Delimiter != "|" || format.Quote != "'" || format.dateStyle() != "ISO, DMY" {
+		t.Errorf("Unexpected format %+v, %v", format, err)
+	}
+
+	for _, m := range []map[string]string{
+		{"format": "excel"},
+		{"header": "maybe"},
+		{"delimiter": ",,"},
+		{"quote": "tab"},
+		{"encoding": "utf 8'"},
+		{"date_format": "DD.MM.YY"},
+	} {
+		if _, err := d.fileFormat(m, tables); err == nil {
+			t.Errorf("Expected an error for manifest entry %v", m)
+		}
+	}
+}
+
+func TestSqlLiteral(t *testing.T) {
+	for s, expected := range map[string]string{"|": "'|'", "'": "''''", "\t": `E'\t'`, `\N`: `E'\\N'`} {
+		if literal := sqlLiteral(s); literal != expected {
+			t.Errorf("sqlLiteral(%q) = %s; expected %s", s, literal, expected)
+		}
+	}
+}
+
+func TestNullPolicies(t *testing.T) {
+	tables, err := parseTableDefinitions(fixtureTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Database{LoadOptions: LoadOptions{NullPolicies: map[string]NullPolicy{
+		"person":                     `\N`,
+		"person.person_source_value": EmptyString,
+		"visit_payer.plan_name":      EmptyString,
+	}}}
+
+	format, err := d.fileFormat(map[string]string{"table": "person"}, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.NullPolicy != `\N` || !reflect.DeepEqual(format.ColumnNullPolicies, map[string]NullPolicy{"person_source_value": EmptyString}) {
+		t.Errorf("Unexpected NULL policies %+v", format)
+	}
+	for _, test := range []struct {
+		column, value string
+		isNull        bool
+	}{
+		{"person_id", `\N`, true},
+		{"person_id", "", false},
+		{"person_source_value", `\N`, false},
+		{"person_source_value", "", false},
+	} {
+		if format.isNull(test.column, test.value) != test.isNull {
+			t.Errorf("Expected isNull(%s, %q) to be %t", test.column, test.value, test.isNull)
+		}
+	}
+
+	format, err = d.fileFormat(map[string]string{"table": "person", "null_policy": "empty-is-null; person_id = empty-is-empty"}, tables)
+	if err != nil || format.NullPolicy != NullIfEmpty || format.columnNullPolicy("person_id") != EmptyString || format.columnNullPolicy("person_source_value") != EmptyString {
+		t.Errorf("Unexpected NULL policies %+v, %v", format, err)
+	}
+	if format.nullString() != "" || !format.isNull("year_of_birth", "") {
+		t.Errorf("Expected empty fields of year_of_birth to be NULL")
+	}
+
+	format, err = d.fileFormat(map[string]string{"table": "person", "null": "NULL"}, tables)
+	if err != nil || format.NullPolicy != "" || format.nullString() != "NULL" || format.columnNullPolicy("year_of_birth") != "NULL" {
+		t.Errorf("Expected the manifest's null marker to apply, got %+v, %v", format, err)
+	}
+
+	// A column's null token overrides the default policy, and the data is converted for COPY.
+	format, err = d.fileFormat(map[string]string{"table": "visit_payer", "null_policy": "plan_name=NA"}, tables)
+	if err != nil || format.columnNullPolicy("visit_payer_id") != NullIfEmpty || format.columnNullPolicy("plan_name") != "NA" || !format.needsNullConversion() {
+		t.Fatalf("Unexpected NULL policies %+v, %v", format, err)
+	}
+	var b bytes.Buffer
+	if err = convertNulls(&b, strings.NewReader("visit_payer_id,plan_name\n1,NA\n,\"\"\n"), []string{"visit_payer_id", "plan_name"}, format); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "visit_payer_id,plan_name\n1,\\N\n\\N,\"\"\n"; b.String() != expected {
+		t.Errorf("Converted %q; expected %q", b.String(), expected)
+	}
+	if format, err = d.fileFormat(map[string]string{"table": "person", "null_policy": "gender_concept_id=NULL"}, tables); err != nil || !format.needsNullConversion() {
+		t.Errorf("Expected different null tokens to be converted, got %+v, %v", format, err)
+	}
+
+	for _, m := range []map[string]string{
+		{"table": "person", "null_policy": "empty-is-nul"},
+		{"table": "person", "null_policy": "gender_concept_id=N,A"},
+	} {
+		if _, err := d.fileFormat(m, tables); err == nil {
+			t.Errorf("Expected an error for manifest entry %v", m)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isJSONLinesFile reports whether `fileName` is a JSON Lines file (.jsonl or .ndjson), which may be compressed (see
+// openDataFile).
+func isJSONLinesFile(fileName string) bool {
+	name := strings.ToLower(fileName)
+	if _, ok := decompressors[filepath.Ext(name)]; ok {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	ext := filepath.Ext(name)
+	return ext == ".jsonl" || ext == ".ndjson"
+}
+
+// parseJSONLine parses a line of JSON Lines data, which must be an object, returning its values by key.
+func parseJSONLine(line []byte) (map[string]json.RawMessage, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(line, &object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("not an object")
+	}
+	return object, nil
+}
+
+// jsonLineKeys returns the keys of the JSON object `line`, sorted.
+func jsonLineKeys(line string) ([]string, error) {
+	object, err := parseJSONLine([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// jsonLinesKeys returns the keys of the first object in the JSON Lines data read from `r`, sorted. Later objects may
+// have other keys.
+func jsonLinesKeys(r io.Reader) ([]string, error) {
+	bufferedReader := bufio.NewReader(r)
+	for {
+		line, err := bufferedReader.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			return jsonLineKeys(line)
+		}
+		if err == io.EOF {
+			return nil, fmt.Errorf("no objects")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// jsonValueText returns a JSON value as text for COPY, with strings unquoted, and whether it is null or missing.
+// Numbers, booleans, objects and arrays keep their JSON representation.
+func jsonValueText(value json.RawMessage) (string, bool, error) {
+	value = bytes.TrimSpace(value)
+	switch {
+	case len(value) == 0 || string(value) == "null":
+		return "", true, nil
+	case value[0] == '"':
+		var s string
+		err := json.Unmarshal(value, &s)
+		return s, false, err
+	}
+	return string(value), false, nil
+}
+
+// writeJSONLinesCsv writes the JSON Lines data read from `r` to `w` as CSV in convertedFormat with a header of
+// `columns`, taking each column's value from the key of the same name. Values are NULL if they are null or missing,
+// or according to the NULL policies of `format` (see isConvertedNull). It returns the number of objects with each key
+// that is not a column.
+func writeJSONLinesCsv(w io.Writer, r io.Reader, columns []string, format FileFormat) (map[string]int, error) {
+	csvWriter := newConvertedCsvWriter(w)
+	if err := csvWriter.write(columns, nil); err != nil {
+		return nil, err
+	}
+
+	isColumn := make(map[string]bool)
+	for _, column := range columns {
+		isColumn[column] = true
+	}
+	extraKeys := make(map[string]int)
+	record := make([]string, len(columns))
+	nulls := make([]bool, len(columns))
+	bufferedReader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := bufferedReader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			object, err := parseJSONLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: %v", lineNumber, err)
+			}
+			for i, column := range columns {
+				text, isNull, err := jsonValueText(object[column])
+				if err != nil {
+					return nil, fmt.Errorf("Line %d: key `%s`: %v", lineNumber, column, err)
+				}
+				record[i], nulls[i] = text, isNull || format.isConvertedNull(column, text)
+			}
+			for key := range object {
+				if !isColumn[key] {
+					extraKeys[key]++
+				}
+			}
+			if err = csvWriter.write(record, nulls); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	return extraKeys, csvWriter.flush()
+}
+
+// copyJSONLines loads the JSON Lines data read from `r` (named `name`, for messages) into table `t`, converting each
+// object to a CSV record of the table's columns as the data is streamed to `psql` (see copyStream). Keys that are not
+// columns of the table are reported as warnings. Of `format`, only the NULL policies apply.
+func copyJSONLines(databaseUrl string, searchPath string, t *tableDefinition, name string, r io.Reader, format FileFormat, rejects *rejectFilter) error {
+	var columns []string
+	for _, c := range t.columns {
+		columns = append(columns, c.name)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	written := make(chan map[string]int, 1)
+	go func() {
+		extraKeys, err := writeJSONLinesCsv(pipeWriter, r, columns, format)
+		pipeWriter.CloseWithError(err)
+		written <- extraKeys
+	}()
+	err := copyStream(databaseUrl, searchPath, t.name, name, pipeReader, convertedFormat, rejects)
+	pipeReader.Close()
+	if err != nil {
+		return err
+	}
+	extraKeys := <-written
+
+	var keys []string
+	for key := range extraKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		log.Warn(fmt.Sprintf("%s: key `%s` in %d object(s) is not a column of %s and was not loaded", name, key, extraKeys[key], t.name))
+	}
+	return nil
+}
+
+// copyJSONLinesFile loads the JSON Lines file `fileName`, which may be compressed (see openDataFile), into table `t`.
+func copyJSONLinesFile(databaseUrl string, searchPath string, t *tableDefinition, fileName string, format FileFormat, rejects *rejectFilter) error {
+	fileReader, err := openDataFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+	return copyJSONLines(databaseUrl, searchPath, t, fileName, fileReader, format, rejects)
+}
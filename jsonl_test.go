@@ -0,0 +1,95 @@
+package database
+
+import (
+	"bytes"
+	"github.com/infomodels/datadirectory"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsJSONLinesFile(t *testing.T) {
+	for fileName, expected := range map[string]bool{"person.jsonl": true, "person.NDJSON": true, "person.jsonl.gz": true, "person.json": false, "person.csv.gz": false} {
+		if isJSONLinesFile(fileName) != expected {
+			t.Errorf("isJSONLinesFile(%s) is not %t", fileName, expected)
+		}
+	}
+}
+
+func TestWriteJSONLinesCsv(t *testing.T) {
+	data := `{"person_id": 1, "year_of_birth": 2001, "person_source_value": "a,\"b\"", "shoe_size": 9}
+
+{"person_id": 2, "year_of_birth": null, "pn_gestational_age": 38.5, "shoe_size": 10, "hat_size": "M"}
+{"person_id": 3, "person_source_value": {"site": "x"}}
+{"person_id": 4, "person_source_value": ""}`
+	columns := []string{"person_id", "year_of_birth", "person_source_value", "pn_gestational_age"}
+	var b bytes.Buffer
+	extraKeys, err := writeJSONLinesCsv(&b, strings.NewReader(data), columns, FileFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "person_id,year_of_birth,person_source_value,pn_gestational_age\n" +
+		"1,2001,\"a,\"\"b\"\"\",\\N\n" +
+		"2,\\N,\\N,38.5\n" +
+		"3,\\N,\"{\"\"site\"\": \"\"x\"\"}\",\\N\n" +
+		"4,\\N,\"\",\\N\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+	if !reflect.DeepEqual(extraKeys, map[string]int{"shoe_size": 2, "hat_size": 1}) {
+		t.Errorf("Unexpected extra keys %v", extraKeys)
+	}
+
+	// NULL policies that are set make other values NULL too.
+	b.Reset()
+	format := FileFormat{NullPolicy: "NA", ColumnNullPolicies: map[string]NullPolicy{"person_source_value": NullIfEmpty}}
+	if _, err = writeJSONLinesCsv(&b, strings.NewReader(`{"person_id": 5, "year_of_birth": "NA", "person_source_value": "", "pn_gestational_age": ""}`), columns, format); err != nil {
+		t.Fatal(err)
+	}
+	if expected = "person_id,year_of_birth,person_source_value,pn_gestational_age\n5,\\N,\\N,\"\"\n"; b.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+
+	for _, data := range []string{"{\"person_id\": 1}\n[1, 2]\n", "{\"person_id\": 1}\n{\"person_id\": \n"} {
+		if _, err = writeJSONLinesCsv(&b, strings.NewReader(data), []string{"person_id"}, FileFormat{}); err == nil || !strings.HasPrefix(err.Error(), "Line 2:") {
+			t.Errorf("Expected an error on line 2 of %q, got %v", data, err)
+		}
+	}
+}
+
+func TestJSONLinesKeys(t *testing.T) {
+	keys, err := jsonLinesKeys(strings.NewReader("\n{\"year_of_birth\": 2001, \"person_id\": 1}\n{\"other\": 1}\n"))
+	if err != nil || !reflect.DeepEqual(keys, []string{"person_id", "year_of_birth"}) {
+		t.Errorf("Unexpected keys %v, %v", keys, err)
+	}
+	if _, err = jsonLinesKeys(strings.NewReader("\n")); err == nil {
+		t.Error("Expected an error for data without objects")
+	}
+}
+
+func TestValidateManifestJSONLines(t *testing.T) {
+	d, dir := newFixtureDatabase(t, map[string]string{
+		"person.jsonl":  `{"person_id": 1, "gender_concept_id": 8507, "year_of_birth": 2001}`,
+		"person2.jsonl": `{"person_id": 1, "year_of_birth": 2001, "shoe_size": 9}`,
+		"person3.jsonl": "null\n",
+	})
+	dataDirectory := &datadirectory.DataDirectory{DirPath: dir, RecordMaps: []map[string]string{
+		{"table": "person", "filename": "person.jsonl"},
+	}}
+	err := d.ValidateManifest(dataDirectory)
+	if err != nil {
+		t.Errorf("Valid manifest rejected: %v", err)
+	}
+
+	// Keys that are not columns are only warned about, and NOT NULL values are checked when loading.
+	dataDirectory.RecordMaps[0]["filename"] = "person2.jsonl"
+	if err = d.ValidateManifest(dataDirectory); err != nil {
+		t.Errorf("Manifest with extra and missing keys rejected: %v", err)
+	}
+
+	dataDirectory.RecordMaps[0]["filename"] = "person3.jsonl"
+	err = d.ValidateManifest(dataDirectory)
+	if manifestErr, ok := err.(*ManifestError); !ok || !reflect.DeepEqual(manifestErr.Problems, []string{"person3.jsonl: not an object"}) {
+		t.Errorf("Expected a problem with the first line of person3.jsonl, got %v", err)
+	}
+}
@@ -1,79 +1,104 @@
 package database
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/csv"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/infomodels/datadirectory"
 	"github.com/lib/pq" // PostgreSQL database driver
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 )
 
-// columnNamesFromCsvFile returns the column headings from the CSV `fileName`.
-func columnNamesFromCsvFile(fileName string) ([]string, error) {
-	fileReader, err := os.Open(fileName)
+// columnNamesFromCsvFile returns the column headings from the CSV `fileName`, in `format`, which may be compressed (see openDataFile).
+func columnNamesFromCsvFile(fileName string, format FileFormat) ([]string, error) {
+	fileReader, err := openDataFile(fileName)
 	if err != nil {
 		return nil, err
 	}
 	defer fileReader.Close()
 
-	csvReader := csv.NewReader(fileReader)
-
-	record, err := csvReader.Read()
+	record, _, err := readCsvHeader(fileReader, format)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading first row of `%s`: %v", fileName, err)
 	}
 	return record, nil
 }
 
-// lineCounter counts the number of physical text lines returned by a Reader.
-// See http://stackoverflow.com/a/24563853/390663.
-// As long as our csv files are not allowed to include newlines in
-// fields, this approach is legitimate. If the final line is not
-// terminated by a newline, it is still counted.
-func lineCounter(r io.Reader) (int, error) {
-	buf := make([]byte, 32*1024)
-	count := 0
-	lineSep := []byte{'\n'}
-	var lastByte byte
-	lastByte = '\n'
+// readCsvHeader reads the header of the CSV data in `r`, in `format`, returning the column names and a reader of all
+// the data, header included, so that the data can be streamed in a single pass.
+func readCsvHeader(r io.Reader, format FileFormat) ([]string, io.Reader, error) {
+	bufferedReader := bufio.NewReader(r)
+	line, err := bufferedReader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, nil, err
+	}
+	record, err := parseCsvHeader(line, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, io.MultiReader(strings.NewReader(line), bufferedReader), nil
+}
 
-	for {
-		c, err := r.Read(buf)
-		if c > 0 {
-			lastByte = buf[c-1]
-		}
-		count += bytes.Count(buf[:c], lineSep)
+// parseCsvHeader returns the column names in the header `line`, in `format` (see FileFormat.headerColumns).
+func parseCsvHeader(line string, format FileFormat) ([]string, error) {
+	record, err := format.newRecordReader(strings.NewReader(strings.TrimPrefix(line, byteOrderMark))).read(true)
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty header")
+	} else if err != nil {
+		return nil, err
+	}
+	return format.headerColumns(record), nil
+}
 
-		switch {
-		case err == io.EOF:
-			if lastByte != '\n' {
-				log.Warn(fmt.Sprintf("Last byte in buffer is '%v'", lastByte))
-				count += 1
-			}
-			return count, nil
+// The Unicode byte order mark, with which some programs begin UTF-8 files.
+const byteOrderMark = "\ufeff"
+
+// normalizeHeader returns the header `name` without any byte order mark or surrounding space, in lower case, as
+// PostgreSQL folds unquoted column names.
+func normalizeHeader(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, byteOrderMark)))
+}
+
+// csvRecordCount returns the number of data records (excluding any header) in the CSV data read from `r`, in `format`.
+// Unlike a count of physical lines, this is correct for quoted fields containing newlines. Empty lines are counted,
+// since COPY reads each as a row with a single NULL field.
+func csvRecordCount(r io.Reader, format FileFormat) (int, error) {
+	recordReader := format.newRecordReader(r)
 
-		case err != nil:
-			return count, err
+	count := 0
+	for {
+		if _, _, err := recordReader.readLine(false); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
 		}
+		count++
+	}
+	if count > 0 && !format.NoHeader {
+		count-- // Account for header
 	}
+	return count, nil
 }
 
-// rowsInFile returns the number of physical lines in a file.
-func rowsInFile(fileName string) (int, error) {
-	fileReader, err := os.Open(fileName)
-	if err != nil {
-		return 0, err
+// Pattern matching the row count reported by COPY, e.g. "COPY 1148".
+var copyOutputPattern = regexp.MustCompile(`(?m)^COPY (\d+)\s*$`)
+
+// copiedRows returns the number of rows reported by psql in the output of a COPY command.
+func copiedRows(output string) (int, error) {
+	matches := copyOutputPattern.FindStringSubmatch(output)
+	if matches == nil {
+		return 0, fmt.Errorf("No row count in COPY output: %s", strings.TrimSpace(output))
 	}
-	defer fileReader.Close()
-	return lineCounter(fileReader)
+	return strconv.Atoi(matches[1])
 }
 
 func rowsInTable(databaseUrl string, searchPath string, table string) (int, error) {
@@ -114,19 +139,71 @@ type CopyCommandArgs struct {
 	SearchPath  string
 	Table       string
 	CsvFile     string
-	WaitGroup   sync.WaitGroup
+	Format      FileFormat
+
+	definition *tableDefinition // Definition of the table, for loading a file that is converted (see isConvertedFile)
+	rejects    *rejectFilter    // Filter of invalid rows, for a tolerant load
+}
+
+// isConvertedFile reports whether the data file `fileName` is converted to CSV as it is loaded, according to the
+// table definition, rather than read in a FileFormat: Parquet and JSON Lines files.
+func isConvertedFile(fileName string) bool {
+	return isParquetFile(fileName) || isJSONLinesFile(fileName)
 }
 
-// copyCommand returns an exec.Command for loading a CSV data file into a database using `psql` via the shell.
+// copyCommand loads a CSV data file, which may be compressed (see openDataFile), into a database using `psql`.
 // CSV files are assumed to be named {table}.csv within a top-level directory in the zip file.
-// The column names are first extracted from the CSV file so we assign columns in the CSV file to the correct columns in the table.
-func copyCommand(databaseUrl string, searchPath string, table string, csvFile string, wg sync.WaitGroup) error {
+func copyCommand(databaseUrl string, searchPath string, table string, csvFile string, format FileFormat, rejects *rejectFilter) error {
+	fileReader, err := openDataFile(csvFile)
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+	return copyStream(databaseUrl, searchPath, table, csvFile, fileReader, format, rejects)
+}
+
+// copyStream loads the CSV data read from `r` (named `name`, for messages), in `format`, into a database by streaming it to `psql`.
+// The column names are first extracted from the CSV header so we assign columns in the CSV data to the correct columns in the table;
+// data without a header must be in the order of `format.Columns`.
+//
+// The load is verified by comparing the number of rows COPY reports with the number of records in the CSV data, counted
+// as it is streamed, and with the growth of the table, so that loading into a non-empty table works.
+//
+// If `rejects` is not nil, the data is streamed through it (see rejectFilter), so that invalid rows are rejected
+// rather than failing the load. Data whose NULL policies COPY cannot apply is streamed through convertNulls (see
+// FileFormat.needsNullConversion). If reading the data fails, `psql` is killed, so that nothing is loaded.
+func copyStream(databaseUrl string, searchPath string, table string, name string, r io.Reader, format FileFormat, rejects *rejectFilter) error {
 
 	log.Info(fmt.Sprintf("Loading %s (search_path: %s)", table, searchPath))
 
-	columnNames, err := columnNamesFromCsvFile(csvFile)
-	if err != nil {
-		return err
+	columnNames, data := format.Columns, r
+	if !format.NoHeader {
+		var err error
+		if columnNames, data, err = readCsvHeader(r, format); err != nil {
+			return fmt.Errorf("Error reading first row of `%s`: %v", name, err)
+		}
+	} else if len(columnNames) == 0 {
+		return fmt.Errorf("No columns given for `%s`, which has no header", name)
+	}
+
+	rejected := make(chan int, 1)
+	if rejects != nil {
+		filtered, filterWriter := io.Pipe()
+		go func(data io.Reader, format FileFormat) {
+			n, err := rejects.filter(filterWriter, data, name, columnNames, format)
+			filterWriter.CloseWithError(err)
+			rejected <- n
+		}(data, format)
+		defer filtered.Close()
+		data, format = filtered, filteredFormat(format)
+	}
+	if format.needsNullConversion() {
+		converted, convertWriter := io.Pipe()
+		go func(data io.Reader, format FileFormat) {
+			convertWriter.CloseWithError(convertNulls(convertWriter, data, columnNames, format))
+		}(data, format)
+		defer converted.Close()
+		data, format = converted, nullConvertedFormat(format)
 	}
 
 	if _, err := exec.LookPath("psql"); err != nil {
@@ -146,36 +223,77 @@ func copyCommand(databaseUrl string, searchPath string, table string, csvFile st
 		return err
 	}
 
-	cmdStr := fmt.Sprintf(`psql "%s" -c "\COPY %s.%s(%s) FROM '%s' (FORMAT csv, HEADER true, ENCODING 'utf-8', FORCE_NULL(%s))"`, connectionString, primarySchema, table, columns, csvFile, columns)
+	rowsBefore, err := rowsInTable(databaseUrl, searchPath, table)
+	if err != nil {
+		return fmt.Errorf("Cannot load %s.%s: %v", primarySchema, table, err)
+	}
+
+	copySql := fmt.Sprintf(`\COPY %s.%s(%s) FROM pstdin (%s)`, primarySchema, table, columns, format.copyOptions(columnNames))
 
-	cmd := exec.Command("sh", "-c", cmdStr)
+	cmd := exec.Command("psql", connectionString, "-c", copySql)
+	if style := format.dateStyle(); style != "" {
+		cmd.Env = append(os.Environ(), "PGDATESTYLE="+style)
+	}
 
-	var e bytes.Buffer
+	// Count the records as psql reads them.
+	type countResult struct {
+		count int
+		err   error
+	}
+	countReader, countWriter := io.Pipe()
+	counted := make(chan countResult, 1)
+	go func() {
+		count, err := csvRecordCount(countReader, format)
+		io.Copy(ioutil.Discard, countReader) // Keep psql's input flowing after an error
+		counted <- countResult{count, err}
+	}()
+
+	var o, e bytes.Buffer
+	input := &abortingReader{r: io.TeeReader(data, countWriter), cmd: cmd}
+	cmd.Stdin = input
+	cmd.Stdout = &o
 	cmd.Stderr = &e
 
 	err = cmd.Run()
-	if err != nil {
-		return fmt.Errorf("Error running command with `sh -c`: %v (STDERR: %s)", cmdStr, err, string(e.Bytes()))
+	countWriter.Close()
+	result := <-counted
+	if input.err != nil {
+		return fmt.Errorf("Error reading `%s`, so nothing was loaded into %s.%s: %v", name, primarySchema, table, input.err)
+	} else if err != nil {
+		return fmt.Errorf("Error running `psql -c %s`: %v (STDERR: %s)", copySql, err, string(e.Bytes()))
 	}
 
-	actualRows, err := rowsInTable(databaseUrl, searchPath, table)
+	copied, err := copiedRows(o.String())
 	if err != nil {
-		return fmt.Errorf("Load for %s.%s nominally worked, but counting the number of rows failed: %v", primarySchema, table, err)
+		return fmt.Errorf("Load for %s.%s nominally worked, but: %v", primarySchema, table, err)
+	}
+
+	if result.err != nil {
+		return fmt.Errorf("Load for %s.%s nominally worked, but counting the records in `%s` failed: %v", primarySchema, table, name, result.err)
 	}
+	expectedRows := result.count
 
-	expectedRows, err := rowsInFile(csvFile)
-	expectedRows -= 1 // Account for header
+	rowsAfter, err := rowsInTable(databaseUrl, searchPath, table)
 	if err != nil {
-		return fmt.Errorf("Load for %s.%s nominally worked, but counting the number of lines in the csv file failed: %v", primarySchema, table, err)
+		return fmt.Errorf("Load for %s.%s nominally worked, but counting the number of rows failed: %v", primarySchema, table, err)
 	}
 
-	if actualRows != expectedRows {
-		err = fmt.Errorf("Number of rows in %s.%s (%d) does not equal the number of lines (%d) in the input file", primarySchema, table, actualRows, expectedRows)
+	if copied != expectedRows {
+		err = fmt.Errorf("Number of rows copied into %s.%s (%d) does not equal the number of records (%d) in the input file", primarySchema, table, copied, expectedRows)
+	} else if rowsAfter-rowsBefore != copied {
+		err = fmt.Errorf("Number of rows in %s.%s grew by %d (from %d), not by the %d rows copied", primarySchema, table, rowsAfter-rowsBefore, rowsBefore, copied)
+	}
+	if err != nil {
 		log.Error(fmt.Sprintf("In copyCommand: %v", err))
 		return err
 	}
 
-	log.Info(fmt.Sprintf("Loaded %d rows into %s.%s", actualRows, primarySchema, table))
+	log.Info(fmt.Sprintf("Loaded %d rows into %s.%s", copied, primarySchema, table))
+	if rejects != nil {
+		if n := <-rejected; n > 0 {
+			log.Warn(fmt.Sprintf("Rejected %d invalid rows of `%s`; see %s", n, name, rejects.fileName))
+		}
+	}
 
 	log.Info(fmt.Sprintf("Vacuuming %s.%s", primarySchema, table))
 	analyze(databaseUrl, primarySchema, table)
@@ -183,27 +301,36 @@ func copyCommand(databaseUrl string, searchPath string, table string, csvFile st
 	return nil
 }
 
-// versionToShorthand - given a version string such as "X.Y.Z", return "XY"
-// TODO: this is an unscalable convention, obviously
-func versionToShorthand(version string) (string, error) {
-	parts := strings.Split(version, ".")
-	if len(parts) != 2 && len(parts) != 3 {
-		return "", fmt.Errorf("Version string must be like X.Y or X.Y.Z, not '%s'", version)
+// abortingReader reads the input of a `psql` command from `r`, killing the command if reading fails. Otherwise, the
+// command would see the end of its input and commit whatever had been copied.
+type abortingReader struct {
+	r   io.Reader
+	cmd *exec.Cmd
+	err error // The error reading `r`, if any
+}
+
+func (a *abortingReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if err != nil && err != io.EOF {
+		a.err = err
+		a.cmd.Process.Kill()
 	}
-	return parts[0] + parts[1], nil
+	return n, err
 }
 
 // databaseName returns a database name, given a version string, e.g. '21' for '2.1' or '2.1.3'
 // `modelVersion` is the PEDSnet model version: X.Y.Z or X.Y
-func databaseName(modelVersion string) (shortVersion string, err error) {
-	if shortVersion, err = versionToShorthand(modelVersion); err != nil {
-		return
+func databaseName(modelVersion string) (string, error) {
+	v, err := ParseModelVersion(modelVersion)
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("pedsnet_dcc_v%s", shortVersion), nil
+	return fmt.Sprintf("pedsnet_dcc_v%s", v.Shorthand()), nil
 }
 
-// load does the work for Load below
-func (d *Database) load(datadirectory *datadirectory.DataDirectory) error {
+// load does the work for Load below, loading each file of the manifest in its format in `formats` into its table in
+// `tables`.
+func (d *Database) load(datadirectory *datadirectory.DataDirectory, tables map[string]*tableDefinition, formats []FileFormat) error {
 	var err error
 
 	// We will parallelize our loads, using a concurrency of 4, or the number in the PREPDB_JOBS environment variable
@@ -219,13 +346,23 @@ func (d *Database) load(datadirectory *datadirectory.DataDirectory) error {
 		}
 	}
 
+	rejectFilters := d.rejectFilters(datadirectory.RecordMaps, tables)
+
 	// spawn worker goroutines and define our worker function
 	var wg sync.WaitGroup
 	for i := 0; i < numJobs; i++ {
 		wg.Add(1)
 		go func(n int) {
 			for args := range tasks {
-				err := copyCommand(args.DatabaseUrl, args.SearchPath, args.Table, args.CsvFile, args.WaitGroup)
+				var err error
+				switch {
+				case isParquetFile(args.CsvFile):
+					err = copyParquet(args.DatabaseUrl, args.SearchPath, args.definition, args.CsvFile, args.Format, args.rejects)
+				case isJSONLinesFile(args.CsvFile):
+					err = copyJSONLinesFile(args.DatabaseUrl, args.SearchPath, args.definition, args.CsvFile, args.Format, args.rejects)
+				default:
+					err = copyCommand(args.DatabaseUrl, args.SearchPath, args.Table, args.CsvFile, args.Format, args.rejects)
+				}
 				if err != nil {
 					taskErrors <- err
 				}
@@ -236,7 +373,7 @@ func (d *Database) load(datadirectory *datadirectory.DataDirectory) error {
 
 	// Now create our loading tasks by iterating through the datadirectory metadata/manifest
 
-	for _, m := range datadirectory.RecordMaps {
+	for i, m := range datadirectory.RecordMaps {
 		table := m["table"]
 		fileName := path.Join(datadirectory.DirPath, m["filename"])
 		copyArgs := &CopyCommandArgs{
@@ -244,7 +381,11 @@ func (d *Database) load(datadirectory *datadirectory.DataDirectory) error {
 			SearchPath:  d.SearchPath,
 			Table:       table,
 			CsvFile:     fileName,
-			WaitGroup:   wg}
+			Format:      formats[i],
+			rejects:     rejectFilters[table]}
+		if isConvertedFile(fileName) {
+			copyArgs.definition = tables[table]
+		}
 		tasks <- copyArgs
 	} // end for all files
 
@@ -257,18 +398,85 @@ func (d *Database) load(datadirectory *datadirectory.DataDirectory) error {
 	for err := range taskErrors {
 		masterError += err.Error() + "\n"
 	}
+	if err = closeRejectFilters(rejectFilters); err != nil {
+		masterError += err.Error() + "\n"
+	}
 	if masterError != "" {
 		masterError += "\n"
 	}
 	if masterError != "" {
-		return fmt.Errorf(masterError)
+		return fmt.Errorf("%s", masterError)
 	}
 
 	return nil
 } // end load
 
+// LoadOptions control how Load and LoadPackage read data files.
+type LoadOptions struct {
+	Format FileFormat // Format of data files whose manifest entries do not say otherwise (see FileFormats); CSV by default.
+
+	// NullPolicies are the NULL policies (see NullPolicy) of tables, keyed by table name, and of columns, keyed by
+	// "table.column", e.g. {"person.person_source_value": EmptyString}. A manifest entry may override them (see
+	// FileFormat).
+	NullPolicies map[string]NullPolicy
+
+	// HeaderMap gives the columns of data file headers that are not their names, keyed by header, for any table, or by
+	// "table.header", e.g. {"PatientID": "person_id"}. Headers are matched ignoring case and surrounding space (see
+	// normalizeHeader). A manifest entry may add to them (see FileFormat).
+	HeaderMap map[string]string
+
+	// Tolerant loads the valid rows of each file, checking each value against its column's type before it is sent to
+	// the database, and writes the invalid rows, with their line numbers and the reasons, to a reject file for each
+	// table, {table}.rejects.csv, rather than failing the file's load.
+	Tolerant   bool
+	RejectDir  string // Directory of the reject files; the working directory by default.
+	MaxRejects int    // Rows of a file that may be rejected in a tolerant load before its load fails; 0 for no limit.
+
+	// StrictRecording fails a load that cannot be recorded in the `load_log` and `version_history` tables, rather than
+	// logging a warning. The data is loaded either way.
+	StrictRecording bool
+}
+
 // Load populates data model tables by shelling out to psql.
-// `dataDirectory` specifies a directory of CSV files and a manifest file that maps tables to files.
+// `dataDirectory` specifies a directory of CSV files, which may be compressed (.gz, .bz2, .zst or .xz), and a manifest file that maps tables to files.
+// Each file is read in the format given by its manifest entry, or d.LoadOptions.Format (see FileFormat). Files may
+// also be Parquet files (.parquet), whose values are converted according to the column types of their schemas, or
+// JSON Lines files (.jsonl or .ndjson), each line an object keyed by column name; missing keys are loaded as NULL and
+// keys that are not columns are reported as warnings. Invalid rows may be rejected rather than failing the load (see
+// LoadOptions.Tolerant).
+// The manifest is validated (see ValidateManifest) and the files' checksums verified (see VerifyChecksums) before
+// anything is loaded. Each file is recorded in the `load_log` table, and the load in the `version_history` table (see
+// recordLoad).
 func (d *Database) Load(dataDirectory *datadirectory.DataDirectory) (err error) {
-	return d.load(dataDirectory)
+	tables, err := d.tableDefinitions()
+	if err != nil {
+		return
+	}
+	formats, err := d.validateManifest(dataDirectory.RecordMaps, tables, directoryHeaders(dataDirectory.DirPath))
+	if err != nil {
+		return
+	}
+	files, err := VerifyChecksums(dataDirectory)
+	if err != nil {
+		return
+	}
+	if err = d.load(dataDirectory, tables, formats); err != nil {
+		return
+	}
+	return d.recordLoad(files)
+}
+
+// recordLoad records the loaded `files` in the `load_log` table and the load in the `version_history` table. The data
+// is committed by then, so a failure to record it is only logged, lest the load be retried and the data loaded twice,
+// unless d.LoadOptions.StrictRecording.
+func (d *Database) recordLoad(files []*VerifiedFile) error {
+	err := d.recordLoadedFiles(files)
+	if err == nil {
+		err = d.RecordOperation("load")
+	}
+	if err != nil && !d.LoadOptions.StrictRecording {
+		log.Warn(fmt.Sprintf("The data was loaded, but the load was not recorded: %v", err))
+		return nil
+	}
+	return err
 }
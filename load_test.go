@@ -0,0 +1,156 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCsvRecordCount(t *testing.T) {
+	for data, expected := range map[string]int{
+		"concept_id,concept_name\n1,\"Multi\nline\"\n2,Plain\n": 2,
+		"concept_id,concept_name\n1,No final newline":           1,
+		"concept_id,concept_name\n":                             0,
+		"concept_id\n1\n\n2\n":                                  3,
+		"":                                                      0,
+	} {
+		if count, err := csvRecordCount(strings.NewReader(data), FileFormat{}); err != nil || count != expected {
+			t.Errorf("csvRecordCount(%q) = %d, %v; expected %d", data, count, err, expected)
+		}
+	}
+}
+
+func TestReadCsvHeader(t *testing.T) {
+	data := "person_id,\"person_source_value\"\n1,x\n"
+	columns, r, err := readCsvHeader(strings.NewReader(data), FileFormat{})
+	if err != nil || !reflect.DeepEqual(columns, []string{"person_id", "person_source_value"}) {
+		t.Errorf("Unexpected header %v, %v", columns, err)
+	}
+	if all, _ := ioutil.ReadAll(r); string(all) != data {
+		t.Errorf("Data not preserved: %q", all)
+	}
+	if _, _, err = readCsvHeader(strings.NewReader(""), FileFormat{}); err == nil {
+		t.Error("Expected an error for empty data")
+	}
+}
+
+func TestCopiedRows(t *testing.T) {
+	if n, err := copiedRows("COPY 1148\n"); err != nil || n != 1148 {
+		t.Errorf("copiedRows = %d, %v; expected 1148", n, err)
+	}
+	if _, err := copiedRows("Timing is on.\n"); err == nil {
+		t.Error("Expected an error for output without a row count")
+	}
+}
+
+func TestOpenDataFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := "person_id,person_source_value\n1,\"Multi\nline\"\n2,Plain\n"
+	compressors := map[string]func(w io.Writer) (io.WriteCloser, error){
+		".csv":     func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil },
+		".csv.gz":  func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		".csv.zst": func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+		".csv.xz":  func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) },
+	}
+	fileNames := []string{"test_resources/person.csv.bz2"}
+	for ext, compressor := range compressors {
+		var b bytes.Buffer
+		w, err := compressor(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, data)
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		fileName := filepath.Join(dir, "person"+ext)
+		if err = ioutil.WriteFile(fileName, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+
+	for _, fileName := range fileNames {
+		columns, err := columnNamesFromCsvFile(fileName, FileFormat{})
+		if err != nil || !reflect.DeepEqual(columns, []string{"person_id", "person_source_value"}) {
+			t.Errorf("%s: unexpected columns %v, %v", fileName, columns, err)
+		}
+		r, err := openDataFile(fileName)
+		if err != nil {
+			t.Errorf("%s: %v", fileName, err)
+			continue
+		}
+		if count, err := csvRecordCount(r, FileFormat{}); err != nil || count != 2 {
+			t.Errorf("%s: counted %d records, %v", fileName, count, err)
+		}
+		r.Close()
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestHeaderColumns(t *testing.T) {
+	format := FileFormat{HeaderMap: map[string]string{"patientid": "person_id"}}
+	columns, err := parseCsvHeader("\ufeff\"Gender_Concept_ID\", PatientID ,year_of_birth\r\n", format)
+	if err != nil || !reflect.DeepEqual(columns, []string{"gender_concept_id", "person_id", "year_of_birth"}) {
+		t.Errorf("Unexpected columns %v, %v", columns, err)
+	}
+
+	tables, err := parseTableDefinitions(fixtureTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Database{LoadOptions: LoadOptions{HeaderMap: map[string]string{
+		"ID":        "id",
+		"person.ID": "person_id",
+		"Sex":       "gender_concept_id",
+	}}}
+	format, err = d.fileFormat(map[string]string{"table": "person", "header_map": "Birth Year = year_of_birth"}, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"id": "person_id", "sex": "gender_concept_id", "birth year": "year_of_birth"}
+	if !reflect.DeepEqual(format.HeaderMap, expected) {
+		t.Errorf("Unexpected header map %v; expected %v", format.HeaderMap, expected)
+	}
+	if _, err = d.fileFormat(map[string]string{"table": "person", "header_map": "year_of_birth"}, tables); err == nil {
+		t.Error("Expected an error for a header mapping without a column")
+	}
+
+	problems := headerProblems("person.csv", tables["person"], []string{"person_id", "gender_concept_id", "year_of_birth", "shoe_size", "hat_size"})
+	if !reflect.DeepEqual(problems, []string{"person.csv: columns `shoe_size`, `hat_size` are not in table `person`"}) {
+		t.Errorf("Unexpected problems %q", problems)
+	}
+}
+
+func TestRecordLoad(t *testing.T) {
+	files := []*VerifiedFile{{Table: "person", FileName: "person.csv", Size: 42}}
+	d, e := newRecordingDatabase()
+	e.Fail("INSERT INTO version_history", errors.New(`relation "version_history" does not exist`))
+	if err := d.recordLoad(files); err != nil {
+		t.Errorf("Expected a failure to record the load to be ignored, got %v", err)
+	}
+
+	d.LoadOptions.StrictRecording = true
+	if err := d.recordLoad(files); err == nil || !strings.Contains(err.Error(), "version_history") {
+		t.Errorf("Expected a failure to record the load, got %v", err)
+	}
+}
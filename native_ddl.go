@@ -0,0 +1,216 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sqlDialect describes how DDL is written for one SQL dialect.
+type sqlDialect struct {
+	types          map[string]string // Data model type to SQL type; "%d" verbs take the field's length, or precision and scale.
+	sizedTypes     map[string]string // Data model type to SQL type for fields with a length or precision, if different.
+	inlineFKs      bool              // Foreign keys can only be declared in CREATE TABLE (SQLite).
+	dropIndexOn    bool              // DROP INDEX requires an ON clause naming the table.
+	dropForeignKey string            // Clause dropping a foreign key constraint, if not DROP CONSTRAINT.
+}
+
+// Dialects for which DDL can be generated natively, keyed by the data-models-sqlalchemy dialect name.
+var sqlDialects = map[string]*sqlDialect{
+	"postgresql": {
+		types: map[string]string{
+			"integer": "INTEGER", "bigint": "BIGINT", "boolean": "BOOLEAN", "float": "DOUBLE PRECISION",
+			"decimal": "NUMERIC", "string": "TEXT", "text": "TEXT", "clob": "TEXT",
+			"date": "DATE", "time": "TIME WITHOUT TIME ZONE", "datetime": "TIMESTAMP WITHOUT TIME ZONE",
+		},
+		sizedTypes: map[string]string{"decimal": "NUMERIC(%d, %d)", "string": "VARCHAR(%d)"},
+	},
+	"mysql": {
+		types: map[string]string{
+			"integer": "INTEGER", "bigint": "BIGINT", "boolean": "BOOL", "float": "DOUBLE",
+			"decimal": "DECIMAL", "string": "TEXT", "text": "TEXT", "clob": "LONGTEXT",
+			"date": "DATE", "time": "TIME", "datetime": "DATETIME",
+		},
+		sizedTypes:     map[string]string{"decimal": "DECIMAL(%d, %d)", "string": "VARCHAR(%d)"},
+		dropIndexOn:    true,
+		dropForeignKey: "DROP FOREIGN KEY",
+	},
+	"mssql": {
+		types: map[string]string{
+			"integer": "INTEGER", "bigint": "BIGINT", "boolean": "BIT", "float": "FLOAT",
+			"decimal": "NUMERIC", "string": "VARCHAR(max)", "text": "VARCHAR(max)", "clob": "VARCHAR(max)",
+			"date": "DATE", "time": "TIME", "datetime": "DATETIME",
+		},
+		sizedTypes:  map[string]string{"decimal": "NUMERIC(%d, %d)", "string": "VARCHAR(%d)"},
+		dropIndexOn: true,
+	},
+	"oracle": {
+		types: map[string]string{
+			"integer": "INTEGER", "bigint": "NUMBER(19)", "boolean": "NUMBER(1)", "float": "FLOAT",
+			"decimal": "NUMBER", "string": "CLOB", "text": "CLOB", "clob": "CLOB",
+			"date": "DATE", "time": "DATE", "datetime": "DATE",
+		},
+		sizedTypes: map[string]string{"decimal": "NUMBER(%d, %d)", "string": "VARCHAR2(%d)"},
+	},
+	"sqlite": {
+		types: map[string]string{
+			"integer": "INTEGER", "bigint": "BIGINT", "boolean": "BOOLEAN", "float": "FLOAT",
+			"decimal": "NUMERIC", "string": "VARCHAR", "text": "TEXT", "clob": "TEXT",
+			"date": "DATE", "time": "TIME", "datetime": "DATETIME",
+		},
+		sizedTypes: map[string]string{"decimal": "NUMERIC(%d, %d)", "string": "VARCHAR(%d)"},
+		inlineFKs:  true,
+	},
+}
+
+// NativeDialects returns the names of the dialects supported by GenerateDDL, sorted.
+func NativeDialects() []string {
+	var names []string
+	for name := range sqlDialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versionHistoryTable is the table in which operations on the model are recorded, which
+// data-models-sqlalchemy adds to the tables of every model.
+var versionHistoryTable = &DataModelTable{
+	Name: "version_history",
+	Fields: []*DataModelField{
+		{Name: "operation", Type: "string", Length: 100},
+		{Name: "model", Type: "string", Length: 16, Required: true},
+		{Name: "model_version", Type: "string", Length: 50, Required: true},
+		{Name: "dms_version", Type: "string", Length: 50},
+		{Name: "dmsa_version", Type: "string", Length: 50},
+		{Name: "datetime", Type: "datetime", Required: true},
+	},
+}
+
+// columnType returns the SQL type of `field` in dialect `dialect`.
+func (dialect *sqlDialect) columnType(field *DataModelField) (string, error) {
+	sqlType, ok := dialect.types[field.Type]
+	if !ok {
+		return "", fmt.Errorf("Unsupported type '%s' for field %s", field.Type, field.Name)
+	}
+	if sized, ok := dialect.sizedTypes[field.Type]; ok {
+		switch {
+		case field.Type == "decimal" && field.Precision > 0:
+			sqlType = fmt.Sprintf(sized, field.Precision, field.Scale)
+		case field.Type != "decimal" && field.Length > 0:
+			sqlType = fmt.Sprintf(sized, field.Length)
+		}
+	}
+	return sqlType, nil
+}
+
+// createTableSql returns the CREATE TABLE statement for `table`, including its primary key and, if the dialect
+// requires it, its foreign keys.
+func (dialect *sqlDialect) createTableSql(m *DataModel, table *DataModelTable) (string, error) {
+	var defs []string
+	for _, field := range table.Fields {
+		sqlType, err := dialect.columnType(field)
+		if err != nil {
+			return "", fmt.Errorf("Table %s: %v", table.Name, err)
+		}
+		def := field.Name + " " + sqlType
+		if field.Required {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	for _, pk := range m.Constraints.PrimaryKeys {
+		if pk.Table == table.Name {
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", pk.Name, strings.Join(pk.Fields, ", ")))
+		}
+	}
+	if dialect.inlineFKs {
+		for _, fk := range m.Constraints.ForeignKeys {
+			if fk.SourceTable == table.Name {
+				defs = append(defs, fmt.Sprintf("CONSTRAINT %s FOREIGN KEY(%s) REFERENCES %s (%s)", fk.Name, fk.SourceField, fk.TargetTable, fk.TargetField))
+			}
+		}
+	}
+	return fmt.Sprintf("\nCREATE TABLE %s (\n\t%s\n)", table.Name, strings.Join(defs, ", \n\t")), nil
+}
+
+// GenerateDDL returns the SQL statements for `ddlOperator` ("ddl" or "drop") and `ddlOperand` ("tables", "indexes" or
+// "constraints") for data model `m` in SQL dialect `dialect` (see NativeDialects). The statements are shaped like those
+// from data-models-sqlalchemy, so they can be used in its place.
+func GenerateDDL(m *DataModel, dialect string, ddlOperator string, ddlOperand string) ([]string, error) {
+	sd, ok := sqlDialects[dialect]
+	if !ok {
+		return nil, fmt.Errorf("Cannot generate DDL for SQL dialect '%s'", dialect)
+	}
+	if ddlOperator != "ddl" && ddlOperator != "drop" {
+		return nil, fmt.Errorf("Invalid DDL operator '%s'", ddlOperator)
+	}
+	constraints := m.Constraints
+	if constraints == nil {
+		constraints = new(DataModelConstraints)
+	}
+	model := *m
+	model.Constraints = constraints
+
+	tables := append([]*DataModelTable{}, m.Tables...)
+	if m.Table(versionHistoryTable.Name) == nil {
+		tables = append(tables, versionHistoryTable)
+	}
+
+	var stmts []string
+	switch ddlOperand {
+	case "tables":
+		for _, table := range tables {
+			if ddlOperator == "drop" {
+				stmts = append(stmts, "DROP TABLE "+table.Name)
+				continue
+			}
+			stmt, err := sd.createTableSql(&model, table)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+		}
+	case "indexes":
+		for _, index := range m.Indexes {
+			if ddlOperator == "drop" {
+				stmt := "DROP INDEX " + index.Name
+				if sd.dropIndexOn {
+					stmt += " ON " + index.Table
+				}
+				stmts = append(stmts, stmt)
+				continue
+			}
+			unique := ""
+			if index.Unique {
+				unique = "UNIQUE "
+			}
+			stmts = append(stmts, fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, index.Name, index.Table, strings.Join(index.Fields, ", ")))
+		}
+	case "constraints":
+		if sd.inlineFKs {
+			break
+		}
+		for _, fk := range constraints.ForeignKeys {
+			if ddlOperator == "drop" {
+				drop := "DROP CONSTRAINT"
+				if sd.dropForeignKey != "" {
+					drop = sd.dropForeignKey
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s %s %s", fk.SourceTable, drop, fk.Name))
+				continue
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY(%s) REFERENCES %s (%s)", fk.SourceTable, fk.Name, fk.SourceField, fk.TargetTable, fk.TargetField))
+		}
+	default:
+		return nil, fmt.Errorf("Invalid DDL operand '%s'", ddlOperand)
+	}
+
+	if ddlOperator == "drop" {
+		// Drop in the reverse of the order of creation.
+		for i, j := 0, len(stmts)-1; i < j; i, j = i+1, j-1 {
+			stmts[i], stmts[j] = stmts[j], stmts[i]
+		}
+	}
+	return stmts, nil
+}
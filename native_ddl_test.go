@@ -0,0 +1,99 @@
+package database
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func readTestDataModel(t *testing.T) *DataModel {
+	data, err := ioutil.ReadFile("test_resources/pedsnet_2.2.0_model.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(DataModel)
+	if err = json.Unmarshal(data, m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestGenerateDDL(t *testing.T) {
+	m := readTestDataModel(t)
+
+	stmts, err := GenerateDDL(m, "postgresql", "ddl", "tables")
+	if err != nil {
+		t.Fatalf("GenerateDDL failed: %v", err)
+	}
+	tables, err := parseTableDefinitions(stmts)
+	if err != nil {
+		t.Fatalf("Generated tables cannot be parsed: %v", err)
+	}
+	if len(tables) != len(m.Tables)+1 || tables["version_history"] == nil {
+		t.Fatalf("Expected %d tables including version_history, got %d", len(m.Tables)+1, len(tables))
+	}
+	concept := tables["concept"]
+	if c := concept.column("concept_name"); c == nil || c.sqlType != "VARCHAR(255)" || !c.notNull {
+		t.Errorf("concept_name generated incorrectly: %+v", c)
+	}
+	if c := concept.column("standard_concept"); c == nil || c.notNull {
+		t.Errorf("standard_concept generated incorrectly: %+v", c)
+	}
+	if len(concept.constraints) != 1 || !strings.Contains(concept.constraints[0], "PRIMARY KEY (concept_id)") {
+		t.Errorf("Unexpected concept constraints: %v", concept.constraints)
+	}
+
+	stmts, err = GenerateDDL(m, "postgresql", "ddl", "indexes")
+	if err != nil {
+		t.Fatalf("GenerateDDL failed: %v", err)
+	}
+	indexes, err := parseEntityDefinitions(stmts, upgradeIndexPatterns)
+	if err != nil || len(indexes) != len(m.Indexes) {
+		t.Errorf("Expected %d parseable indexes, got %d: %v", len(m.Indexes), len(indexes), err)
+	}
+
+	stmts, err = GenerateDDL(m, "postgresql", "ddl", "constraints")
+	if err != nil {
+		t.Fatalf("GenerateDDL failed: %v", err)
+	}
+	constraints, err := parseEntityDefinitions(stmts, upgradeConstraintPatterns)
+	if err != nil || len(constraints) != len(m.Constraints.ForeignKeys) {
+		t.Errorf("Expected %d parseable constraints, got %d: %v", len(m.Constraints.ForeignKeys), len(constraints), err)
+	}
+
+	stmts, err = GenerateDDL(m, "postgresql", "drop", "tables")
+	if err != nil || stmts[0] != "DROP TABLE version_history" {
+		t.Errorf("Tables should be dropped in reverse order: %v, %v", stmts, err)
+	}
+}
+
+func TestGenerateDDLDialects(t *testing.T) {
+	m := readTestDataModel(t)
+
+	stmts, err := GenerateDDL(m, "mysql", "drop", "constraints")
+	if err != nil || !strings.Contains(stmts[0], " DROP FOREIGN KEY ") {
+		t.Errorf("Unexpected mysql constraint drop: %v, %v", stmts, err)
+	}
+	stmts, err = GenerateDDL(m, "oracle", "ddl", "tables")
+	if err != nil || !strings.Contains(strings.Join(stmts, ";"), "concept_name VARCHAR2(255) NOT NULL") {
+		t.Errorf("Unexpected oracle tables: %v", err)
+	}
+
+	// SQLite foreign keys can only be created with their tables.
+	if stmts, err = GenerateDDL(m, "sqlite", "ddl", "constraints"); err != nil || len(stmts) != 0 {
+		t.Errorf("Expected no sqlite constraint statements, got %v, %v", stmts, err)
+	}
+	stmts, err = GenerateDDL(m, "sqlite", "ddl", "tables")
+	if err != nil || !strings.Contains(strings.Join(stmts, ";"), "FOREIGN KEY(") {
+		t.Errorf("Expected inline sqlite foreign keys: %v", err)
+	}
+
+	if _, err = GenerateDDL(m, "db2", "ddl", "tables"); err == nil {
+		t.Error("GenerateDDL accepted an unsupported dialect")
+	}
+	m.Tables[0].Fields[0].Type = "blob"
+	if _, err = GenerateDDL(m, "postgresql", "ddl", "tables"); err == nil {
+		t.Error("GenerateDDL accepted an unsupported type")
+	}
+}
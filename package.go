@@ -0,0 +1,305 @@
+package database
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Name of the manifest within a data package.
+const manifestFileName = "metadata.csv"
+
+// packageMember describes a file within a data package archive.
+type packageMember struct {
+	firstLine string            // The CSV header, if the file has one
+	headerErr error             // Error reading the first line
+	digests   map[string]string // Hex digest of the file as archived, by the checksum algorithms named in the manifest
+	size      int64
+}
+
+// packageContents describes a data package archive, as found by scanPackage.
+type packageContents struct {
+	manifest []map[string]string       // The manifest's records
+	dir      string                    // Directory containing the manifest, to which its file names are relative
+	members  map[string]*packageMember // Files by name within the archive
+}
+
+// walkPackage calls `fn` with the name and contents of each regular file in the tar or zip archive `packagePath`, in order.
+// Tar archives may be compressed (e.g. .tar.gz or .tgz; see openDataFile).
+func walkPackage(packagePath string, fn func(name string, r io.Reader) error) error {
+	if strings.HasSuffix(strings.ToLower(packagePath), ".zip") {
+		z, err := zip.OpenReader(packagePath)
+		if err != nil {
+			return err
+		}
+		defer z.Close()
+		for _, f := range z.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("Error reading `%s` in `%s`: %v", f.Name, packagePath, err)
+			}
+			err = fn(path.Clean(f.Name), r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	archiveName := packagePath
+	if strings.HasSuffix(strings.ToLower(archiveName), ".tgz") {
+		archiveName = archiveName[:len(archiveName)-len(".tgz")] + ".tar.gz"
+	}
+	f, err := os.Open(packagePath)
+	if err != nil {
+		return err
+	}
+	r, err := decompressedReader(archiveName, f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	t := tar.NewReader(r)
+	for {
+		h, err := t.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("Error reading `%s`: %v", packagePath, err)
+		}
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err = fn(path.Clean(h.Name), t); err != nil {
+			return err
+		}
+	}
+}
+
+// byteCounter is a Writer counting the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// parseManifest returns the records of a manifest as maps from field name to value.
+func parseManifest(r io.Reader) ([]map[string]string, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1 // Missing trailing fields are empty
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var recordMaps []map[string]string
+	for i := 1; i < len(records); i++ {
+		m := make(map[string]string)
+		for j, field := range records[0] {
+			if j < len(records[i]) {
+				m[field] = records[i][j]
+			}
+		}
+		recordMaps = append(recordMaps, m)
+	}
+	return recordMaps, nil
+}
+
+// errManifestRead stops walkPackage once the manifest has been read.
+var errManifestRead = errors.New("manifest read")
+
+// checksumAlgorithms returns the checksum algorithms named in the manifest, keyed by file name within the archive.
+// Invalid checksums are ignored here, and reported by verifyChecksums.
+func (c *packageContents) checksumAlgorithms() map[string][]string {
+	algorithms := make(map[string][]string)
+	for _, m := range c.manifest {
+		if algorithm, _, err := parseChecksum(m["checksum"]); err == nil {
+			name := path.Join(c.dir, m["filename"])
+			algorithms[name] = append(algorithms[name], algorithm)
+		}
+	}
+	return algorithms
+}
+
+// scanPackage reads the manifest of the data package archive `packagePath`, and the first line, size and the
+// checksums named in the manifest of each of its files, without extracting anything. Tar archives must be read in
+// order, so this is a separate pass, preceded by one reading as far as the manifest, usually the first file.
+func scanPackage(packagePath string) (*packageContents, error) {
+	contents := &packageContents{members: make(map[string]*packageMember)}
+
+	err := walkPackage(packagePath, func(name string, r io.Reader) error {
+		if path.Base(name) != manifestFileName {
+			return nil
+		}
+		contents.dir = path.Dir(name)
+		var err error
+		if contents.manifest, err = parseManifest(r); err != nil {
+			return fmt.Errorf("Error reading %s in `%s`: %v", name, packagePath, err)
+		}
+		return errManifestRead
+	})
+	if err == nil {
+		return nil, fmt.Errorf("No %s in `%s`", manifestFileName, packagePath)
+	} else if err != errManifestRead {
+		return nil, err
+	}
+
+	algorithms := contents.checksumAlgorithms()
+	manifestFound := false
+	err = walkPackage(packagePath, func(name string, r io.Reader) error {
+		if path.Base(name) == manifestFileName {
+			if manifestFound {
+				return fmt.Errorf("More than one %s in `%s`", manifestFileName, packagePath)
+			}
+			manifestFound = true
+			return nil
+		}
+
+		// Checksum the file as archived, while reading its header from the decompressed data.
+		var (
+			member  = &packageMember{digests: make(map[string]string)}
+			hashes  = make(map[string]hash.Hash)
+			size    byteCounter
+			writers = []io.Writer{&size}
+		)
+		for _, algorithm := range algorithms[name] {
+			hashes[algorithm] = checksumHashes[algorithm]()
+			writers = append(writers, hashes[algorithm])
+		}
+		tee := io.TeeReader(r, io.MultiWriter(writers...))
+
+		if data, err := decompressedReader(name, ioutil.NopCloser(tee)); err != nil {
+			member.headerErr = err
+		} else {
+			member.firstLine, member.headerErr = bufio.NewReader(data).ReadString('\n')
+			if member.headerErr == io.EOF && member.firstLine != "" {
+				member.headerErr = nil
+			}
+			data.Close()
+		}
+		if _, err := io.Copy(ioutil.Discard, tee); err != nil {
+			return fmt.Errorf("Error reading `%s` in `%s`: %v", name, packagePath, err)
+		}
+
+		for algorithm, h := range hashes {
+			member.digests[algorithm] = hex.EncodeToString(h.Sum(nil))
+		}
+		member.size = int64(size)
+		contents.members[name] = member
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// member returns the archived file named `fileName` in the manifest, or nil if there is none.
+func (c *packageContents) member(fileName string) *packageMember {
+	return c.members[path.Join(c.dir, fileName)]
+}
+
+// LoadPackage populates data model tables from the data package archive `packagePath`, a tar archive (which may be
+// compressed, e.g. .tar.gz) or a zip archive containing CSV files and a metadata.csv manifest that maps tables to files.
+// Files are streamed straight out of the archive into the database, without being extracted to disk, each in the
+// format given by its manifest entry or d.LoadOptions.Format (see FileFormat). JSON Lines files and tolerant loads
+// are supported as for Load, but Parquet files are not, since they cannot be read as a stream.
+//
+// As for Load, the manifest is validated and the files' checksums are verified before anything is loaded, and the load
+// is recorded in the `load_log` and `version_history` tables (see recordLoad). This requires an extra pass through the
+// archive, since a tar archive's manifest may follow the files it describes. Files are loaded one at a time, in archive
+// order.
+func (d *Database) LoadPackage(packagePath string) error {
+	contents, err := scanPackage(packagePath)
+	if err != nil {
+		return err
+	}
+
+	tables, err := d.tableDefinitions()
+	if err != nil {
+		return err
+	}
+	formats, err := d.validateManifest(contents.manifest, tables, func(fileName string, format FileFormat, t *tableDefinition) ([]string, error) {
+		m := contents.member(fileName)
+		if m == nil {
+			return nil, os.ErrNotExist
+		} else if isParquetFile(fileName) {
+			return nil, fmt.Errorf("Parquet files cannot be streamed from a data package; extract it and use Load")
+		} else if m.headerErr != nil {
+			return nil, m.headerErr
+		} else if isJSONLinesFile(fileName) {
+			return jsonLineKeys(m.firstLine)
+		}
+		return parseCsvHeader(m.firstLine, format)
+	})
+	if err != nil {
+		return err
+	}
+	files, err := verifyChecksums(contents.manifest, func(fileName string, algorithm string) (string, int64, error) {
+		m := contents.member(fileName)
+		if m == nil {
+			return "", 0, fmt.Errorf("file does not exist")
+		}
+		return m.digests[algorithm], m.size, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]int) // Name within the archive to manifest entry
+	for i, m := range contents.manifest {
+		entries[path.Join(contents.dir, m["filename"])] = i
+	}
+
+	rejectFilters := d.rejectFilters(contents.manifest, tables)
+
+	var loadErrors []string
+	err = walkPackage(packagePath, func(name string, r io.Reader) error {
+		i, ok := entries[name]
+		if !ok {
+			return nil
+		}
+		data, err := decompressedReader(name, ioutil.NopCloser(r))
+		if err != nil {
+			loadErrors = append(loadErrors, err.Error())
+			return nil
+		}
+		defer data.Close()
+		table := contents.manifest[i]["table"]
+		if isJSONLinesFile(name) {
+			err = copyJSONLines(d.DatabaseUrl, d.SearchPath, tables[table], name, data, formats[i], rejectFilters[table])
+		} else {
+			err = copyStream(d.DatabaseUrl, d.SearchPath, table, name, data, formats[i], rejectFilters[table])
+		}
+		if err != nil {
+			loadErrors = append(loadErrors, err.Error())
+		}
+		return nil
+	})
+	if closeErr := closeRejectFilters(rejectFilters); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if len(loadErrors) > 0 {
+		return fmt.Errorf("%s", strings.Join(loadErrors, "\n"))
+	}
+
+	return d.recordLoad(files)
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	recordMaps, err := parseManifest(strings.NewReader("table,filename\nperson,person.csv\nvisit_occurrence\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]string{{"table": "person", "filename": "person.csv"}, {"table": "visit_occurrence"}}
+	if !reflect.DeepEqual(recordMaps, expected) {
+		t.Errorf("Unexpected manifest records: %v", recordMaps)
+	}
+}
+
+func TestScanPackageTar(t *testing.T) {
+	contents, err := scanPackage(filepath.Join("test_resources", "pedsnet_vocab.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.dir != "." || len(contents.manifest) != 9 {
+		t.Errorf("Unexpected manifest in `%s` with %d entries", contents.dir, len(contents.manifest))
+	}
+
+	concept := contents.member("concept.csv")
+	if concept == nil {
+		t.Fatal("concept.csv not found")
+	}
+	if concept.headerErr != nil || !strings.HasPrefix(concept.firstLine, "concept_class_id,") {
+		t.Errorf("Unexpected header of concept.csv: %q (%v)", concept.firstLine, concept.headerErr)
+	}
+
+	files, err := verifyChecksums(contents.manifest, func(fileName string, algorithm string) (string, int64, error) {
+		m := contents.member(fileName)
+		return m.digests[algorithm], m.size, nil
+	})
+	if err != nil {
+		t.Errorf("Checksums of archived files do not match the manifest: %v", err)
+	}
+	if len(files) != 9 {
+		t.Errorf("Expected 9 verified files, got %d", len(files))
+	}
+}
+
+func TestScanPackageZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "package")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	packagePath := filepath.Join(dir, "package.zip")
+	f, err := os.Create(packagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	g := gzip.NewWriter(&gz)
+	g.Write([]byte("person_id,year_of_birth\n1,2001\n"))
+	g.Close()
+	digest := md5.Sum(gz.Bytes())
+
+	// The manifest follows the file, whose name is not clean.
+	z := zip.NewWriter(f)
+	w, _ := z.Create("./data/person.csv.gz")
+	w.Write(gz.Bytes())
+	w, _ = z.Create("data/metadata.csv")
+	w.Write([]byte("table,filename,checksum\nperson,person.csv.gz,md5:" + hex.EncodeToString(digest[:]) + "\n"))
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	contents, err := scanPackage(packagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents.dir != "data" {
+		t.Errorf("Expected manifest directory `data`, got `%s`", contents.dir)
+	}
+	person := contents.member("person.csv.gz")
+	if person == nil {
+		t.Fatal("person.csv.gz not found")
+	}
+	if person.firstLine != "person_id,year_of_birth\n" {
+		t.Errorf("Unexpected header of person.csv.gz: %q (%v)", person.firstLine, person.headerErr)
+	}
+	// Only the algorithm named in the manifest is computed.
+	if person.size != int64(gz.Len()) || !reflect.DeepEqual(person.digests, map[string]string{"md5": hex.EncodeToString(digest[:])}) {
+		t.Errorf("Unexpected size %d or digests %v", person.size, person.digests)
+	}
+
+	if _, err = scanPackage(filepath.Join("test_resources", "person.csv.bz2")); err == nil {
+		t.Error("Expected an error scanning a file that is not an archive")
+	}
+}
@@ -0,0 +1,335 @@
+package database
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of Parquet values that may be loaded into columns of each type category (see columnDefinition.category).
+// Values of any kind may be loaded into text columns, and into columns of unrecognized types, which PostgreSQL checks.
+var parquetKindsByCategory = map[string][]string{
+	"integer":   {"integer"},
+	"numeric":   {"integer", "decimal", "float"},
+	"float":     {"integer", "decimal", "float"},
+	"date":      {"date", "timestamp"},
+	"timestamp": {"timestamp", "date"},
+	"time":      {"time"},
+	"boolean":   {"boolean"},
+}
+
+// isParquetFile reports whether `fileName` is a Parquet file, judging by its extension.
+func isParquetFile(fileName string) bool {
+	return strings.ToLower(filepath.Ext(fileName)) == ".parquet"
+}
+
+// openParquetFile opens the Parquet file `fileName`, returning the parsed file and the underlying file, which the
+// caller must close.
+func openParquetFile(fileName string) (*parquet.File, *os.File, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	pf, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("Error reading Parquet file `%s`: %v", fileName, err)
+	}
+	return pf, f, nil
+}
+
+// parquetKind returns the kind of the values of a Parquet column, e.g. "integer" or "timestamp", or "" if they
+// cannot be loaded.
+func parquetKind(t parquet.Type) string {
+	if lt := t.LogicalType(); lt != nil && lt.Value != nil {
+		switch lt.Value.(type) {
+		case *format.StringType, *format.EnumType, *format.JsonType:
+			return "string"
+		case *format.UUIDType:
+			return "uuid"
+		case *format.DecimalType:
+			return "decimal"
+		case *format.DateType:
+			return "date"
+		case *format.TimeType:
+			return "time"
+		case *format.TimestampType:
+			return "timestamp"
+		case *format.IntType:
+			return "integer"
+		}
+		return ""
+	}
+	switch t.Kind() {
+	case parquet.Boolean:
+		return "boolean"
+	case parquet.Int32, parquet.Int64:
+		return "integer"
+	case parquet.Int96:
+		return "timestamp" // Legacy timestamps, e.g. written by Spark
+	case parquet.Float, parquet.Double:
+		return "float"
+	case parquet.ByteArray:
+		return "string"
+	}
+	return ""
+}
+
+// parquetColumns returns the column names of the Parquet file `pf` and a function formatting the values of each
+// column as text for COPY. If `t` is not nil, each column's values must be loadable into the column of table `t`
+// with the same name. Only flat schemas, whose columns are not nested or repeated, are supported.
+func parquetColumns(pf *parquet.File, t *tableDefinition) ([]string, []func(parquet.Value) string, error) {
+	var (
+		names      []string
+		formatters []func(parquet.Value) string
+		problems   []string
+	)
+	for _, field := range pf.Schema().Fields() {
+		name := field.Name()
+		names = append(names, name)
+		formatters = append(formatters, nil)
+		if !field.Leaf() || field.Repeated() {
+			problems = append(problems, fmt.Sprintf("column `%s` is nested or repeated", name))
+			continue
+		}
+		kind := parquetKind(field.Type())
+		if kind == "" {
+			problems = append(problems, fmt.Sprintf("column `%s` has unsupported type %s", name, field.Type()))
+			continue
+		}
+		formatters[len(formatters)-1] = parquetFormatter(kind, field.Type())
+
+		if t == nil {
+			continue
+		}
+		c := t.column(name)
+		if c == nil {
+			continue // Reported with the other header problems
+		}
+		if kinds, ok := parquetKindsByCategory[c.category()]; ok && !containsString(kinds, kind) {
+			problems = append(problems, fmt.Sprintf("column `%s` of type %s cannot be loaded into %s column `%s`", name, field.Type(), c.sqlType, c.name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return names, formatters, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parquetFormatter returns a function formatting non-null Parquet values of `kind` and type `t` as text for COPY.
+func parquetFormatter(kind string, t parquet.Type) func(parquet.Value) string {
+	var lt format.LogicalTypeValue
+	if t.LogicalType() != nil {
+		lt = t.LogicalType().Value
+	}
+
+	switch kind {
+	case "string":
+		return func(v parquet.Value) string { return string(v.ByteArray()) }
+	case "uuid":
+		return func(v parquet.Value) string {
+			s := hex.EncodeToString(v.ByteArray())
+			if len(s) != 32 {
+				return s
+			}
+			return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+		}
+	case "decimal":
+		scale := int(lt.(*format.DecimalType).Scale)
+		return func(v parquet.Value) string { return formatDecimal(parquetUnscaled(v), scale) }
+	case "date":
+		return func(v parquet.Value) string {
+			return time.Unix(int64(v.Int32())*86400, 0).UTC().Format("2006-01-02")
+		}
+	case "time":
+		unit := lt.(*format.TimeType).Unit.Value.Duration()
+		return func(v parquet.Value) string {
+			n := v.Int64()
+			if v.Kind() == parquet.Int32 {
+				n = int64(v.Int32())
+			}
+			return time.Time{}.Add(time.Duration(n) * unit).Format("15:04:05.999999")
+		}
+	case "timestamp":
+		if t.Kind() == parquet.Int96 {
+			return func(v parquet.Value) string {
+				i := v.Int96()
+				days := int64(i[2]) - 2440588 // Julian day of the Unix epoch
+				nanos := int64(i[1])<<32 | int64(i[0])
+				return formatTimestamp(time.Unix(days*86400, nanos).UTC(), false)
+			}
+		}
+		timestamp := lt.(*format.TimestampType)
+		perSecond := int64(time.Second / timestamp.Unit.Value.Duration())
+		return func(v parquet.Value) string {
+			n := v.Int64()
+			return formatTimestamp(time.Unix(n/perSecond, (n%perSecond)*(int64(time.Second)/perSecond)).UTC(), timestamp.IsAdjustedToUTC)
+		}
+	case "integer":
+		if it, ok := lt.(*format.IntType); ok && !it.IsSigned {
+			return func(v parquet.Value) string {
+				if v.Kind() == parquet.Int32 {
+					return strconv.FormatUint(uint64(uint32(v.Int32())), 10)
+				}
+				return strconv.FormatUint(uint64(v.Int64()), 10)
+			}
+		}
+		return func(v parquet.Value) string {
+			if v.Kind() == parquet.Int32 {
+				return strconv.FormatInt(int64(v.Int32()), 10)
+			}
+			return strconv.FormatInt(v.Int64(), 10)
+		}
+	case "float":
+		return func(v parquet.Value) string {
+			if v.Kind() == parquet.Float {
+				return formatFloat(float64(v.Float()), 32)
+			}
+			return formatFloat(v.Double(), 64)
+		}
+	case "boolean":
+		return func(v parquet.Value) string { return strconv.FormatBool(v.Boolean()) }
+	}
+	return nil
+}
+
+// parquetUnscaled returns the unscaled value of a Parquet decimal, which may be stored as an integer or as a
+// big-endian two's complement byte array.
+func parquetUnscaled(v parquet.Value) *big.Int {
+	switch v.Kind() {
+	case parquet.Int32:
+		return big.NewInt(int64(v.Int32()))
+	case parquet.Int64:
+		return big.NewInt(v.Int64())
+	}
+	b := v.ByteArray()
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	return n
+}
+
+// formatDecimal formats the decimal with unscaled value `n` and `scale` digits after the decimal point.
+func formatDecimal(n *big.Int, scale int) string {
+	s := new(big.Int).Abs(n).String()
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if n.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// formatTimestamp formats `t` for PostgreSQL, with a UTC offset if `utc`.
+func formatTimestamp(t time.Time, utc bool) string {
+	s := t.Format("2006-01-02 15:04:05.999999")
+	if utc {
+		s += "+00"
+	}
+	return s
+}
+
+// formatFloat formats a floating point number for PostgreSQL, which spells infinity "Infinity".
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// writeParquetCsv writes the rows of `pf`, row group by row group, to `w` as CSV in convertedFormat with a header of
+// the column `names`, using `formatters` to format the values of each column. Values are NULL if they are null, or
+// according to the NULL policies of `format` (see isConvertedNull).
+func writeParquetCsv(w io.Writer, pf *parquet.File, names []string, formatters []func(parquet.Value) string, format FileFormat) error {
+	csvWriter := newConvertedCsvWriter(w)
+	if err := csvWriter.write(names, nil); err != nil {
+		return err
+	}
+
+	record := make([]string, len(names))
+	nulls := make([]bool, len(names))
+	rows := make([]parquet.Row, 256)
+	for _, rowGroup := range pf.RowGroups() {
+		rowReader := rowGroup.Rows()
+		for {
+			n, err := rowReader.ReadRows(rows)
+			for _, row := range rows[:n] {
+				for i := range record {
+					record[i], nulls[i] = "", true
+				}
+				for _, v := range row {
+					if c := v.Column(); !v.IsNull() && c < len(formatters) {
+						record[c] = formatters[c](v)
+						nulls[c] = format.isConvertedNull(names[c], record[c])
+					}
+				}
+				if writeErr := csvWriter.write(record, nulls); writeErr != nil {
+					rowReader.Close()
+					return writeErr
+				}
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				rowReader.Close()
+				return err
+			}
+		}
+		rowReader.Close()
+	}
+	return csvWriter.flush()
+}
+
+// copyParquet loads the Parquet file `fileName` into table `t`, converting its rows to CSV as they are streamed to
+// `psql` (see copyStream). The Parquet column types must suit the table's (see parquetColumns). Of `format`, only the
+// NULL policies apply.
+func copyParquet(databaseUrl string, searchPath string, t *tableDefinition, fileName string, format FileFormat, rejects *rejectFilter) error {
+	pf, f, err := openParquetFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	names, formatters, err := parquetColumns(pf, t)
+	if err != nil {
+		return fmt.Errorf("Cannot load `%s` into %s: %v", fileName, t.name, err)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeParquetCsv(w, pf, names, formatters, format))
+	}()
+	defer r.Close()
+	return copyStream(databaseUrl, searchPath, t.name, fileName, r, convertedFormat, rejects)
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"bytes"
+	"github.com/infomodels/datadirectory"
+	"github.com/parquet-go/parquet-go"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const parquetVisitTable = `CREATE TABLE visit (
+	visit_id INTEGER NOT NULL,
+	visit_start_date DATE NOT NULL,
+	visit_start_datetime TIMESTAMP WITHOUT TIME ZONE,
+	charge NUMERIC(8, 2),
+	note VARCHAR(20),
+	weight DOUBLE PRECISION
+)`
+
+type parquetVisit struct {
+	VisitId            int32      `parquet:"visit_id"`
+	VisitStartDate     int32      `parquet:"visit_start_date,date"`
+	VisitStartDatetime *time.Time `parquet:"visit_start_datetime,optional,timestamp(microsecond:local)"`
+	Charge             int64      `parquet:"charge,decimal(2:8)"`
+	Note               *string    `parquet:"note,optional"`
+	Weight             float64    `parquet:"weight"`
+}
+
+// writeParquetVisits writes a Parquet file of visits in `dir`, returning its name.
+func writeParquetVisits(t *testing.T, dir string) string {
+	start := time.Date(2020, 1, 1, 8, 30, 0, 500000000, time.UTC)
+	note, empty, nullToken := "Follow-up, \"urgent\"", "", `\N`
+	visits := []parquetVisit{
+		{VisitId: 1, VisitStartDate: 18262, VisitStartDatetime: &start, Charge: 12345, Note: &note, Weight: 72.5},
+		{VisitId: 2, VisitStartDate: -1, Charge: -5, Weight: 3},
+		{VisitId: 3, VisitStartDate: 0, Note: &empty, Weight: 0},
+		{VisitId: 4, VisitStartDate: 0, Note: &nullToken, Weight: 0},
+	}
+	fileName := filepath.Join(dir, "visit.parquet")
+	f, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = parquet.Write(f, visits); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestParquetCsv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := parseTableDefinition(parquetVisitTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pf, f, err := openParquetFile(writeParquetVisits(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	names, formatters, err := parquetColumns(pf, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err = writeParquetCsv(&b, pf, names, formatters, FileFormat{}); err != nil {
+		t.Fatal(err)
+	}
+	// NULLs are written as \N, distinct from empty strings and strings that are \N.
+	expected := "visit_id,visit_start_date,visit_start_datetime,charge,note,weight\n" +
+		"1,2020-01-01,2020-01-01 08:30:00.5,123.45,\"Follow-up, \"\"urgent\"\"\",72.5\n" +
+		"2,1969-12-31,\\N,-0.05,\\N,3\n" +
+		"3,1970-01-01,\\N,0.00,\"\",0\n" +
+		"4,1970-01-01,\\N,0.00,\"\\N\",0\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+	if count, err := csvRecordCount(&b, convertedFormat); err != nil || count != 4 {
+		t.Errorf("Counted %d records, %v; expected 4", count, err)
+	}
+	if options := convertedFormat.copyOptions(names); options != `FORMAT csv, HEADER true, NULL E'\\N', ENCODING 'utf-8'` {
+		t.Errorf("Unexpected COPY options %s", options)
+	}
+
+	mismatched, _ := parseTableDefinition(strings.Replace(parquetVisitTable, "visit_start_date DATE", "visit_start_date INTEGER", 1))
+	if _, _, err = parquetColumns(pf, mismatched); err == nil || !strings.Contains(err.Error(), "column `visit_start_date`") {
+		t.Errorf("Expected an error for a date loaded into an INTEGER column, got %v", err)
+	}
+}
+
+func TestValidateManifestParquet(t *testing.T) {
+	dir := t.TempDir()
+	writeParquetVisits(t, dir)
+	d := &Database{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: fixtureSource([]string{parquetVisitTable}), LoadOptions: LoadOptions{Format: FileFormat{NoHeader: true}}}
+	dataDirectory := &datadirectory.DataDirectory{DirPath: dir, RecordMaps: []map[string]string{
+		{"table": "visit", "filename": "visit.parquet"},
+	}}
+	if err := d.ValidateManifest(dataDirectory); err != nil {
+		t.Errorf("Valid manifest rejected: %v", err)
+	}
+
+	d.DDLSource = fixtureSource([]string{strings.Replace(parquetVisitTable, "note VARCHAR(20),", "", 1)})
+	if err := d.ValidateManifest(dataDirectory); err == nil || !strings.Contains(err.Error(), "column `note` is not in table `visit`") {
+		t.Errorf("Expected an unknown column to be reported, got %v", err)
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	for expected, value := range map[string]parquet.Value{
+		"123.45": parquet.Int32Value(12345),
+		"-0.05":  parquet.Int64Value(-5),
+		"0.00":   parquet.Int64Value(0),
+		"-2.56":  parquet.ByteArrayValue([]byte{0xff, 0x00}),
+		"2.55":   parquet.ByteArrayValue([]byte{0x00, 0xff}),
+	} {
+		if s := formatDecimal(parquetUnscaled(value), 2); s != expected {
+			t.Errorf("Formatted %v as %s; expected %s", value, s, expected)
+		}
+	}
+	if s := formatDecimal(big.NewInt(-7), 0); s != "-7" {
+		t.Errorf("Formatted -7 with scale 0 as %s", s)
+	}
+}
@@ -0,0 +1,171 @@
+// Package pgtest runs throwaway PostgreSQL clusters for integration tests, so that they need no outside database.
+//
+// A cluster is created with initdb in a temporary directory, started with pg_ctl on a free port, and
+// removed entirely by Stop. The PostgreSQL server binaries must be installed; they are found via the
+// PGTEST_BINDIR environment variable, the PATH, `pg_config --bindir`, or the usual Debian location.
+//
+// PostgreSQL refuses to run as root. When the tests run as root, as in many CI containers, the PGTEST_USER
+// environment variable names an unprivileged user to run the cluster as, using runuser.
+//
+// Typical use:
+//
+//	cluster, err := pgtest.Start()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer cluster.Stop()
+//	d, err := database.Open("pedsnet", "2.2.0", cluster.URL, "", dmsaUrl, "", "")
+package pgtest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnavailable is returned by Start if no cluster can be run in this environment.
+var ErrUnavailable = errors.New("PostgreSQL server binaries are not available")
+
+// Cluster is a running throwaway PostgreSQL cluster.
+type Cluster struct {
+	Dir    string // Temporary directory holding the cluster's data, socket and log
+	BinDir string // Directory containing initdb, pg_ctl and psql
+	Port   int    // TCP port on 127.0.0.1
+	URL    string // URL of the `postgres` database, as superuser `postgres`
+
+	user string // User to run the server binaries as (see PGTEST_USER), or "" for the current user
+}
+
+// BinDir returns the directory containing the PostgreSQL server binaries, or ErrUnavailable.
+func BinDir() (string, error) {
+	if dir := os.Getenv("PGTEST_BINDIR"); dir != "" {
+		return dir, nil
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	if out, err := exec.Command("pg_config", "--bindir").Output(); err == nil {
+		dir := strings.TrimSpace(string(out))
+		if _, err := os.Stat(filepath.Join(dir, "initdb")); err == nil {
+			return dir, nil
+		}
+	}
+	// Debian and Ubuntu keep the server binaries out of the PATH; prefer the newest.
+	dirs, _ := filepath.Glob("/usr/lib/postgresql/*/bin")
+	sort.Strings(dirs)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, err := os.Stat(filepath.Join(dirs[i], "initdb")); err == nil {
+			return dirs[i], nil
+		}
+	}
+	return "", ErrUnavailable
+}
+
+// freePort returns a TCP port on 127.0.0.1 that is not currently in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// chownToUser gives the file or directory `name` to the user named `userName`.
+func chownToUser(name string, userName string) error {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return fmt.Errorf("User %s has no numeric user ID: %v", userName, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return fmt.Errorf("User %s has no numeric group ID: %v", userName, err)
+	}
+	return os.Chown(name, uid, gid)
+}
+
+// run runs a PostgreSQL binary, as c.user if set, including its output in any error.
+func (c *Cluster) run(name string, args ...string) error {
+	cmd := exec.Command(filepath.Join(c.BinDir, name), args...)
+	if c.user != "" {
+		cmd = exec.Command("runuser", append([]string{"-u", c.user, "--", filepath.Join(c.BinDir, name)}, args...)...)
+	}
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Error running %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Start creates and starts a cluster. Call Stop when done, even if the tests fail.
+//
+// PostgreSQL refuses to run as root, so ErrUnavailable is returned when running as root without PGTEST_USER, as well
+// as when the binaries cannot be found.
+func Start() (*Cluster, error) {
+	binDir, err := BinDir()
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{BinDir: binDir}
+	if os.Geteuid() == 0 {
+		if c.user = os.Getenv("PGTEST_USER"); c.user == "" {
+			return nil, fmt.Errorf("%w: PostgreSQL cannot be run as root; set PGTEST_USER to run it as another user", ErrUnavailable)
+		}
+	}
+
+	if c.Dir, err = ioutil.TempDir("", "pgtest"); err != nil {
+		return nil, err
+	}
+	if c.user != "" {
+		if err = chownToUser(c.Dir, c.user); err != nil {
+			os.RemoveAll(c.Dir)
+			return nil, err
+		}
+	}
+	if c.Port, err = freePort(); err != nil {
+		os.RemoveAll(c.Dir)
+		return nil, err
+	}
+	c.URL = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", c.Port)
+
+	dataDir := filepath.Join(c.Dir, "data")
+	if err = c.run("initdb", "-D", dataDir, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-locale"); err != nil {
+		os.RemoveAll(c.Dir)
+		return nil, err
+	}
+
+	// Durability is pointless for a throwaway cluster; the socket lives in the temp dir to avoid permission problems.
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c synchronous_commit=off -c full_page_writes=off", c.Port, c.Dir)
+	if err = c.run("pg_ctl", "-D", dataDir, "-o", options, "-l", filepath.Join(c.Dir, "postgresql.log"), "-w", "start"); err != nil {
+		os.RemoveAll(c.Dir)
+		return nil, err
+	}
+	return c, nil
+}
+
+// Stop stops the cluster and removes its directory.
+func (c *Cluster) Stop() error {
+	err := c.run("pg_ctl", "-D", filepath.Join(c.Dir, "data"), "-m", "immediate", "-w", "stop")
+	if removeErr := os.RemoveAll(c.Dir); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Log returns the server log, which is useful when diagnosing failures.
+func (c *Cluster) Log() string {
+	data, _ := ioutil.ReadFile(filepath.Join(c.Dir, "postgresql.log"))
+	return string(data)
+}
@@ -0,0 +1,36 @@
+package pgtest
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+func TestCluster(t *testing.T) {
+	c, err := Start()
+	if errors.Is(err, ErrUnavailable) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	db, err := sql.Open("postgres", c.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err = db.QueryRow("SELECT 1").Scan(&n); err != nil || n != 1 {
+		t.Errorf("Query failed: %v\n%s", err, c.Log())
+	}
+	db.Close()
+
+	if err = c.Stop(); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+	if _, err = os.Stat(c.Dir); !os.IsNotExist(err) {
+		t.Errorf("Cluster directory %s was not removed", c.Dir)
+	}
+}
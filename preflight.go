@@ -0,0 +1,171 @@
+package database
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/infomodels/datadirectory"
+	"os"
+	"path"
+	"strings"
+)
+
+// ManifestError is returned by ValidateManifest, listing every problem found.
+type ManifestError struct {
+	Problems []string
+
+	entryProblems [][]string // The problems of each manifest entry, if known (see validateManifest)
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("Data directory manifest has %d problem(s):\n\t%s", len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+// ValidateManifest checks the manifest of `dataDirectory` against the data model before anything is loaded: every entry
+// must name a table of the model that is selected by the include/exclude patterns, no table may appear twice, each file
+// must be readable, its format (see FileFormat) must be valid, and each file's header (or, for a headerless file, the
+// format's columns) must name only columns of its table, including all NOT NULL columns. Headers are matched ignoring
+// case, surrounding space and any byte order mark, or mapped to columns by LoadOptions.HeaderMap. The objects of a
+// JSON Lines file may have any keys, since those that are not columns are not loaded: they are logged as warnings,
+// and missing values of NOT NULL columns are left to COPY or a tolerant load. All problems are returned at once, in a
+// *ManifestError.
+func (d *Database) ValidateManifest(dataDirectory *datadirectory.DataDirectory) error {
+	tables, err := d.tableDefinitions()
+	if err != nil {
+		return err
+	}
+	_, err = d.validateManifest(dataDirectory.RecordMaps, tables, directoryHeaders(dataDirectory.DirPath))
+	return err
+}
+
+// directoryHeaders returns a function returning the column names of a file in the data directory `dirPath`. For a
+// Parquet file, the function also checks that the file's column types suit table `t` (see parquetColumns). For a
+// JSON Lines file, it returns the keys of the first object.
+func directoryHeaders(dirPath string) func(fileName string, format FileFormat, t *tableDefinition) ([]string, error) {
+	return func(fileName string, format FileFormat, t *tableDefinition) ([]string, error) {
+		if isJSONLinesFile(fileName) {
+			fileReader, err := openDataFile(path.Join(dirPath, fileName))
+			if err != nil {
+				return nil, err
+			}
+			defer fileReader.Close()
+			return jsonLinesKeys(fileReader)
+		}
+		if !isParquetFile(fileName) {
+			return columnNamesFromCsvFile(path.Join(dirPath, fileName), format)
+		}
+		pf, f, err := openParquetFile(path.Join(dirPath, fileName))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		names, _, err := parquetColumns(pf, t)
+		return names, err
+	}
+}
+
+// validateManifest does the work for ValidateManifest, for the manifest entries `recordMaps` and the data model's
+// `tables` (see tableDefinitions), using `header` to obtain the column names of each file. It returns the format of
+// each entry's file, in the order of `recordMaps`, and a *ManifestError if there are problems, in which case the
+// formats of entries with problems may not be valid.
+func (d *Database) validateManifest(recordMaps []map[string]string, tables map[string]*tableDefinition, header func(fileName string, format FileFormat, t *tableDefinition) ([]string, error)) ([]FileFormat, error) {
+	var (
+		problems      []string
+		entryProblems [][]string
+		formats       []FileFormat
+	)
+	seen := make(map[string]string) // Table to file name
+	for _, m := range recordMaps {
+		format, entry := d.manifestEntryProblems(m, tables, header, seen)
+		formats = append(formats, format)
+		entryProblems = append(entryProblems, entry)
+		problems = append(problems, entry...)
+	}
+
+	if len(problems) > 0 {
+		return formats, &ManifestError{Problems: problems, entryProblems: entryProblems}
+	}
+	return formats, nil
+}
+
+// manifestEntryProblems returns the format of the file of the manifest entry `m` and the entry's problems (see
+// validateManifest). `seen` maps the tables of the entries before it to their file names, and `m`'s table is added.
+func (d *Database) manifestEntryProblems(m map[string]string, tables map[string]*tableDefinition, header func(fileName string, format FileFormat, t *tableDefinition) ([]string, error), seen map[string]string) (FileFormat, []string) {
+	var problems []string
+	table, fileName := m["table"], m["filename"]
+	if fileName == "" {
+		return FileFormat{}, []string{fmt.Sprintf("Manifest entry for table `%s` has no file name", table)}
+	}
+
+	t := tables[table]
+	switch {
+	case table == "":
+		problems = append(problems, fmt.Sprintf("%s: no table given", fileName))
+	case t == nil:
+		problems = append(problems, fmt.Sprintf("%s: table `%s` is not in version %s of the %s model", fileName, table, d.ModelVersion, d.Model))
+	case !d.isTableSelected(table):
+		problems = append(problems, fmt.Sprintf("%s: table `%s` is excluded by the include/exclude patterns", fileName, table))
+	case seen[table] != "":
+		problems = append(problems, fmt.Sprintf("%s: table `%s` is also loaded from %s", fileName, table, seen[table]))
+	default:
+		seen[table] = fileName
+	}
+
+	format, err := d.fileFormat(m, tables)
+	if err != nil {
+		return format, append(problems, fmt.Sprintf("%s: %v", fileName, err))
+	}
+
+	columns, err := header(fileName, format, t)
+	if format.NoHeader && !isConvertedFile(fileName) && err == nil {
+		columns = format.Columns
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("file does not exist")
+		}
+		return format, append(problems, fmt.Sprintf("%s: %v", fileName, err))
+	}
+	if t == nil {
+		return format, problems
+	}
+	if isJSONLinesFile(fileName) {
+		for _, key := range columns {
+			if t.column(key) == nil {
+				log.Warn(fmt.Sprintf("%s: key `%s` of the first object is not a column of %s and will not be loaded", fileName, key, t.name))
+			}
+		}
+		return format, problems
+	}
+	return format, append(problems, headerProblems(fileName, t, columns)...)
+}
+
+// headerProblems returns the problems with the header `columns` of `fileName` for loading into table `t`.
+func headerProblems(fileName string, t *tableDefinition, columns []string) []string {
+	var (
+		problems []string
+		unknown  []string
+	)
+	present := make(map[string]bool)
+	for _, column := range columns {
+		if present[column] {
+			problems = append(problems, fmt.Sprintf("%s: column `%s` appears more than once", fileName, column))
+		}
+		present[column] = true
+		if t.column(column) == nil {
+			unknown = append(unknown, "`"+column+"`")
+		}
+	}
+	switch len(unknown) {
+	case 0:
+	case 1:
+		problems = append(problems, fmt.Sprintf("%s: column %s is not in table `%s`", fileName, unknown[0], t.name))
+	default:
+		problems = append(problems, fmt.Sprintf("%s: columns %s are not in table `%s`", fileName, strings.Join(unknown, ", "), t.name))
+	}
+	for _, c := range t.columns {
+		if c.notNull && !present[c.name] {
+			problems = append(problems, fmt.Sprintf("%s: required column `%s` of table `%s` is missing", fileName, c.name, t.name))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"github.com/infomodels/datadirectory"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	d, dir := newFixtureDatabase(t, map[string]string{
+		"person.csv":       "person_id,gender_concept_id,year_of_birth\n1,8507,2001\n",
+		"person_again.csv": "person_id,gender_concept_id,year_of_birth,shoe_size\n",
+		"visit_payer.csv":  "plan_name\n",
+		"location.csv":     "location_id\n",
+	})
+	dataDirectory := &datadirectory.DataDirectory{DirPath: dir, RecordMaps: []map[string]string{
+		{"table": "person", "filename": "person.csv"},
+	}}
+	if err := d.ValidateManifest(dataDirectory); err != nil {
+		t.Errorf("Valid manifest rejected: %v", err)
+	}
+
+	d.excludeTables = regexp.MustCompile("^visit_payer$")
+	dataDirectory.RecordMaps = append(dataDirectory.RecordMaps,
+		map[string]string{"table": "person", "filename": "person_again.csv"},
+		map[string]string{"table": "visit_payer", "filename": "visit_payer.csv"},
+		map[string]string{"table": "location", "filename": "location.csv"},
+		map[string]string{"table": "person", "filename": "missing.csv"},
+	)
+	err := d.ValidateManifest(dataDirectory)
+	manifestErr, ok := err.(*ManifestError)
+	if !ok {
+		t.Fatalf("Expected a ManifestError, got %v", err)
+	}
+	expected := []string{
+		"person_again.csv: table `person` is also loaded from person.csv",
+		"person_again.csv: column `shoe_size` is not in table `person`",
+		"visit_payer.csv: table `visit_payer` is excluded by the include/exclude patterns",
+		"visit_payer.csv: required column `visit_payer_id` of table `visit_payer` is missing",
+		"location.csv: table `location` is not in version 2.2.0 of the pedsnet model",
+		"missing.csv: table `person` is also loaded from person.csv",
+		"missing.csv: file does not exist",
+	}
+	if !reflect.DeepEqual(manifestErr.Problems, expected) {
+		t.Errorf("Unexpected problems:\n%s", strings.Join(manifestErr.Problems, "\n"))
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// rejectFilter filters the rows of a table's data files in a tolerant load (see LoadOptions.Tolerant), passing on
+// the rows that are valid for the table's column types (see valueProblem) and writing the others to the table's
+// reject file, {table}.rejects.csv. The reject file is shared by the table's data files, which may be loaded
+// concurrently, and is created when the first row is rejected.
+type rejectFilter struct {
+	table    *tableDefinition
+	fileName string
+	max      int // Rows of a data file that may be rejected, or 0 for no limit
+
+	mu        sync.Mutex
+	file      *os.File
+	csvWriter *csv.Writer
+}
+
+// rejectFilters returns a rejectFilter for each table of `recordMaps`, keyed by table name, if d.LoadOptions.Tolerant,
+// or nil otherwise.
+func (d *Database) rejectFilters(recordMaps []map[string]string, tables map[string]*tableDefinition) map[string]*rejectFilter {
+	if !d.LoadOptions.Tolerant {
+		return nil
+	}
+	filters := make(map[string]*rejectFilter)
+	for _, m := range recordMaps {
+		table := m["table"]
+		if t := tables[table]; t != nil && filters[table] == nil {
+			filters[table] = &rejectFilter{table: t, fileName: filepath.Join(d.LoadOptions.RejectDir, table+".rejects.csv"), max: d.LoadOptions.MaxRejects}
+		}
+	}
+	return filters
+}
+
+// closeRejectFilters closes the reject files of `filters`, returning the first error.
+func closeRejectFilters(filters map[string]*rejectFilter) error {
+	var err error
+	for _, f := range filters {
+		if closeErr := f.close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// filter copies the records of the data read from `r` (named `name`, for messages), in `format` with columns
+// `columns`, to `w` as CSV with a header, in the format returned by filteredFormat. Records with values that cannot
+// be loaded are written to the reject file instead. It returns the number of rejected records, and fails if there are
+// more than f.max.
+func (f *rejectFilter) filter(w io.Writer, r io.Reader, name string, columns []string, format FileFormat) (int, error) {
+	definitions := make([]*columnDefinition, len(columns))
+	for i, column := range columns {
+		definitions[i] = f.table.column(column)
+	}
+
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(columns); err != nil {
+		return 0, err
+	}
+	recordReader := format.newRecordReader(r)
+	if !format.NoHeader {
+		if _, err := recordReader.read(false); err != nil && err != io.EOF {
+			return 0, err
+		}
+	}
+
+	rejected := 0
+	for {
+		record, err := recordReader.read(true)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return rejected, fmt.Errorf("line %d: %v", recordReader.line, err)
+		}
+		reason := recordProblem(record, columns, definitions, format)
+		if reason == "" {
+			if err = csvWriter.Write(record); err != nil {
+				return rejected, err
+			}
+			continue
+		}
+		rejected++
+		if f.max > 0 && rejected > f.max {
+			return rejected, fmt.Errorf("more than %d rows are invalid, the last on line %d: %s", f.max, recordReader.line, reason)
+		}
+		if err = f.reject(name, recordReader.line, reason, record); err != nil {
+			return rejected, err
+		}
+	}
+	csvWriter.Flush()
+	return rejected, csvWriter.Error()
+}
+
+// filteredFormat returns the format of the data written by rejectFilter.filter from data in `format`.
+func filteredFormat(format FileFormat) FileFormat {
+	return FileFormat{Null: format.Null, Encoding: format.Encoding, DateFormat: format.DateFormat, NullPolicy: format.NullPolicy, ColumnNullPolicies: format.ColumnNullPolicies, exactNull: format.exactNull}
+}
+
+// recordProblem returns why `record`, whose fields are values of `columns`, defined by `definitions` (nil for unknown
+// columns, which are not checked), cannot be loaded, or "" if it can.
+func recordProblem(record []string, columns []string, definitions []*columnDefinition, format FileFormat) string {
+	if len(record) != len(definitions) {
+		return fmt.Sprintf("the row has %d fields, not %d", len(record), len(definitions))
+	}
+	var problems []string
+	for i, c := range definitions {
+		if c == nil {
+			continue
+		}
+		if problem := valueProblem(c, record[i], format.isNull(columns[i], record[i]), format); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// reject writes a record rejected from line `line` of the data named `name` to the reject file, which has the columns
+// file, line, reason and record, the last the record's fields as a line of CSV.
+func (f *rejectFilter) reject(name string, line int, reason string, record []string) error {
+	var b bytes.Buffer
+	recordWriter := csv.NewWriter(&b)
+	recordWriter.Write(record)
+	recordWriter.Flush()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		file, err := os.Create(f.fileName)
+		if err != nil {
+			return err
+		}
+		f.file, f.csvWriter = file, csv.NewWriter(file)
+		f.csvWriter.Write([]string{"file", "line", "reason", "record"})
+	}
+	f.csvWriter.Write([]string{name, fmt.Sprint(line), reason, strings.TrimSuffix(b.String(), "\n")})
+	f.csvWriter.Flush()
+	return f.csvWriter.Error()
+}
+
+// close closes the reject file, if any rows were rejected.
+func (f *rejectFilter) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	if err == nil {
+		log.Warn(fmt.Sprintf("Rows rejected from %s were written to %s", f.table.name, f.fileName))
+	}
+	f.file, f.csvWriter = nil, nil
+	return err
+}
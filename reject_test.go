@@ -0,0 +1,87 @@
+package database
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRejectFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rejects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := parseTableDefinition(validateTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]*tableDefinition{"visit": table}
+	d := &Database{LoadOptions: LoadOptions{Tolerant: true, RejectDir: dir}}
+	filters := d.rejectFilters([]map[string]string{{"table": "visit", "filename": "visit.csv"}}, tables)
+	f := filters["visit"]
+	if f == nil {
+		t.Fatal("No filter for visit")
+	}
+
+	data := "visit_id|visit_start_date|note\n" +
+		"1|2020-01-01|'a|b'\n" +
+		"x|2020-01-01|\n" +
+		"\n" +
+		"3|NULL|'multi\nline'\n" +
+		"4|2020-01-02|NULL\n" +
+		"5|2020-01-03\n"
+	format := FileFormat{Delimiter: "|", Quote: "'", Null: "NULL"}
+	var b bytes.Buffer
+	n, err := f.filter(&b, strings.NewReader(data), "visit.csv", []string{"visit_id", "visit_start_date", "note"}, format)
+	if err != nil || n != 3 {
+		t.Fatalf("Rejected %d rows, %v; expected 3", n, err)
+	}
+	expected := "visit_id,visit_start_date,note\n1,2020-01-01,a|b\n4,2020-01-02,NULL\n"
+	if b.String() != expected {
+		t.Errorf("Unexpected filtered data:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+	if count, err := csvRecordCount(&b, filteredFormat(format)); err != nil || count != 2 {
+		t.Errorf("Counted %d filtered records, %v; expected 2", count, err)
+	}
+	if err = closeRejectFilters(filters); err != nil {
+		t.Fatal(err)
+	}
+
+	rejects, err := ioutil.ReadFile(filepath.Join(dir, "visit.rejects.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := readRecords(t, string(rejects), FileFormat{})
+	if len(records) != 4 || strings.Join(records[0], ",") != "file,line,reason,record" {
+		t.Fatalf("Unexpected rejects %q", records)
+	}
+	for i, line := range []string{"3", "5", "8"} {
+		if records[i+1][0] != "visit.csv" || records[i+1][1] != line {
+			t.Errorf("Expected reject %d from line %s, got %q", i+1, line, records[i+1])
+		}
+	}
+	if !strings.Contains(records[1][2], "value 'x' of column `visit_id`") || records[1][3] != "x,2020-01-01," {
+		t.Errorf("Unexpected reject %q", records[1])
+	}
+	if !strings.Contains(records[2][2], "`visit_start_date` is NOT NULL") {
+		t.Errorf("Unexpected reject %q", records[2])
+	}
+	if records[3][2] != "the row has 2 fields, not 3" {
+		t.Errorf("Unexpected reject %q", records[3])
+	}
+
+	f.max = 2
+	if _, err = f.filter(ioutil.Discard, strings.NewReader(data), "visit.csv", []string{"visit_id", "visit_start_date", "note"}, format); err == nil || !strings.Contains(err.Error(), "more than 2 rows are invalid") {
+		t.Errorf("Expected too many rejects to fail, got %v", err)
+	}
+	closeRejectFilters(filters)
+
+	if (&Database{}).rejectFilters([]map[string]string{{"table": "visit"}}, tables) != nil {
+		t.Error("Expected no filters for a strict load")
+	}
+}
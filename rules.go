@@ -0,0 +1,163 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// DDLRule rewrites, drops or appends SQL statements in the DDL obtained for a data model, before the
+// DDL is filtered by table and executed. Empty selector fields (Model, Version, Dialect, Operator,
+// Operand, Table) match anything.
+//
+// Rules can be registered in Go with RegisterDDLRule or loaded from a JSON file with LoadDDLRules.
+type DDLRule struct {
+	Model     string `json:"model"`     // Model the rule applies to, e.g. "pedsnet".
+	Version   string `json:"version"`   // Range of model versions the rule applies to, e.g. "2.2" or ">=2.1 <3" (see ParseVersionRange).
+	Dialect   string `json:"dialect"`   // SQL dialect the rule applies to, e.g. "postgresql".
+	Operator  string `json:"operator"`  // DDL operator the rule applies to: "ddl" or "drop".
+	Operand   string `json:"operand"`   // DDL operand the rule applies to: "tables", "indexes" or "constraints".
+	Table     string `json:"table"`     // Table whose statements the rule applies to; ignored for "append".
+	Action    string `json:"action"`    // "rewrite", "drop" or "append".
+	Match     string `json:"match"`     // Regexp a statement must match for "rewrite" or "drop"; "" matches every statement.
+	Replace   string `json:"replace"`   // Replacement for the text matched by Match, for "rewrite"; may contain $1-style references.
+	Statement string `json:"statement"` // Statement to add after the others, for "append".
+
+	match *regexp.Regexp
+}
+
+// ddlRules holds the registered rules, in the order they are applied.
+var ddlRules []*DDLRule
+
+// Default rules, registered at startup.
+var defaultDDLRules = []*DDLRule{
+	// Work around a data-models-sqlalchemy problem; the rule will be benign even after the problem is fixed.
+	{Table: "version_history", Operator: "ddl", Operand: "tables", Action: "rewrite", Match: `dms_version VARCHAR\(16\)`, Replace: "dms_version VARCHAR(50)"},
+}
+
+func init() {
+	for _, rule := range defaultDDLRules {
+		if err := RegisterDDLRule(rule); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Pattern capturing the table that a DDL statement creates, alters, drops or indexes.
+var statementTablePattern = regexp.MustCompile(`(?i)(?:CREATE TABLE|ALTER TABLE|DROP TABLE(?: IF EXISTS)?|INSERT INTO|DELETE FROM|\bON)\s+(\w+)`)
+
+// statementTable returns the table a DDL statement applies to, or "" if it can't be determined (e.g. for DROP INDEX).
+func statementTable(stmt string) string {
+	if matches := statementTablePattern.FindStringSubmatch(stmt); matches != nil {
+		return matches[1]
+	}
+	return ""
+}
+
+// compile validates the rule and compiles its Match pattern.
+func (r *DDLRule) compile() error {
+	switch r.Action {
+	case "rewrite", "drop":
+		if r.Match != "" {
+			var err error
+			if r.match, err = regexp.Compile(r.Match); err != nil {
+				return fmt.Errorf("Invalid DDL rule match pattern '%s': %v", r.Match, err)
+			}
+		}
+	case "append":
+		if strings.TrimSpace(r.Statement) == "" {
+			return fmt.Errorf("DDL rule with action 'append' requires a statement")
+		}
+	default:
+		return fmt.Errorf("Invalid DDL rule action '%s'", r.Action)
+	}
+	return nil
+}
+
+// selects returns true if the rule applies to DDL for the given model, version, dialect, operator and operand.
+func (r *DDLRule) selects(model string, version string, dialect string, ddlOperator string, ddlOperand string) bool {
+	return (r.Model == "" || r.Model == model) &&
+		(r.Version == "" || versionInRange(version, r.Version)) &&
+		(r.Dialect == "" || r.Dialect == dialect) &&
+		(r.Operator == "" || r.Operator == ddlOperator) &&
+		(r.Operand == "" || r.Operand == ddlOperand)
+}
+
+// matches returns true if the rule applies to the statement `stmt`.
+func (r *DDLRule) matches(stmt string) bool {
+	if r.Table != "" && statementTable(stmt) != r.Table {
+		return false
+	}
+	return r.match == nil || r.match.MatchString(stmt)
+}
+
+// RegisterDDLRule adds a rule to those applied to all subsequently obtained DDL. Rules are applied in the order registered.
+func RegisterDDLRule(rule *DDLRule) error {
+	if err := rule.compile(); err != nil {
+		return err
+	}
+	ddlRules = append(ddlRules, rule)
+	return nil
+}
+
+// LoadDDLRules registers the rules in the JSON file `fileName`, which should contain an array of objects with the fields of DDLRule.
+func LoadDDLRules(fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var rules []*DDLRule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("Error parsing DDL rules file `%s`: %v", fileName, err)
+	}
+	for _, rule := range rules {
+		if err = RegisterDDLRule(rule); err != nil {
+			return fmt.Errorf("Error in DDL rules file `%s`: %v", fileName, err)
+		}
+	}
+	return nil
+}
+
+// applyDDLRules applies `rules` to the DDL statements `stmts` obtained for the given model, version, dialect, operator and operand.
+func applyDDLRules(rules []*DDLRule, stmts []string, model string, version string, dialect string, ddlOperator string, ddlOperand string) []string {
+	var selected []*DDLRule
+	for _, rule := range rules {
+		if rule.selects(model, version, dialect, ddlOperator, ddlOperand) {
+			selected = append(selected, rule)
+		}
+	}
+	if len(selected) == 0 {
+		return stmts
+	}
+
+	var result []string
+	for _, stmt := range stmts {
+		keep := true
+		for _, rule := range selected {
+			if rule.Action == "append" || !rule.matches(stmt) {
+				continue
+			}
+			if rule.Action == "drop" {
+				keep = false
+				break
+			}
+			if rule.match == nil {
+				stmt = rule.Replace
+			} else {
+				stmt = rule.match.ReplaceAllString(stmt, rule.Replace)
+			}
+		}
+		if keep {
+			result = append(result, stmt)
+		}
+	}
+
+	for _, rule := range selected {
+		if rule.Action == "append" {
+			result = append(result, rule.Statement)
+		}
+	}
+	return result
+}
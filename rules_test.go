@@ -0,0 +1,103 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultDDLRules(t *testing.T) {
+	stmts := []string{
+		"\nCREATE TABLE version_history (\n\toperation VARCHAR(100),\n\tdms_version VARCHAR(16)\n)",
+		"\nCREATE TABLE person (\n\tdms_version VARCHAR(16)\n)",
+	}
+	expected := []string{
+		"\nCREATE TABLE version_history (\n\toperation VARCHAR(100),\n\tdms_version VARCHAR(50)\n)",
+		"\nCREATE TABLE person (\n\tdms_version VARCHAR(16)\n)",
+	}
+	if result := applyDDLRules(ddlRules, stmts, "pedsnet", "2.2.0", "postgresql", "ddl", "tables"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result: %q", result)
+	}
+	if result := applyDDLRules(ddlRules, stmts, "pedsnet", "2.2.0", "postgresql", "ddl", "indexes"); !reflect.DeepEqual(result, stmts) {
+		t.Errorf("Rule applied to wrong operand: %q", result)
+	}
+}
+
+// The DDL of data-models-sqlalchemy declares dms_version VARCHAR(16), as does the fixture; the default rule widens it.
+func TestDefaultDDLRulesFixture(t *testing.T) {
+	d := &Database{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: NewDirSource("test_resources/dmsa")}
+	raw, err := d.DDLSource.Statements(d.Model, d.ModelVersion, ddlDialect, "ddl", "tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := rawDmsaSql(d, d.ModelVersion, "ddl", "tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, stmt := range stmts {
+		if statementTable(stmt) != "version_history" {
+			continue
+		}
+		if !strings.Contains(raw[i], "dms_version VARCHAR(16)") {
+			t.Errorf("Expected the fixture to declare dms_version VARCHAR(16): %s", raw[i])
+		}
+		if expected := strings.Replace(raw[i], "dms_version VARCHAR(16)", "dms_version VARCHAR(50)", 1); stmt != expected {
+			t.Errorf("Unexpected rewritten statement:\n%s\nexpected:\n%s", stmt, expected)
+		}
+		return
+	}
+	t.Error("No version_history table in the fixture")
+}
+
+func TestLoadDDLRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddlrules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "rules.json")
+	rulesJson := `[
+		{"model": "pedsnet", "version": "2.2", "table": "person", "action": "rewrite", "match": "VARCHAR\\(50\\)", "replace": "VARCHAR(256)"},
+		{"model": "pedsnet", "table": "visit_payer", "action": "drop"},
+		{"model": "pedsnet", "operand": "tables", "action": "append", "statement": "ALTER TABLE person ADD COLUMN site_person_id VARCHAR(64)"}
+	]`
+	if err = ioutil.WriteFile(fileName, []byte(rulesJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := ddlRules
+	defer func() { ddlRules = saved }()
+	ddlRules = nil
+
+	if err = LoadDDLRules(fileName); err != nil {
+		t.Fatalf("LoadDDLRules failed: %v", err)
+	}
+
+	stmts := []string{
+		"CREATE TABLE person (person_source_value VARCHAR(50))",
+		"CREATE TABLE visit_payer (plan_name VARCHAR(50))",
+	}
+	expected := []string{
+		"CREATE TABLE person (person_source_value VARCHAR(256))",
+		"ALTER TABLE person ADD COLUMN site_person_id VARCHAR(64)",
+	}
+	if result := applyDDLRules(ddlRules, stmts, "pedsnet", "2.2.1", "postgresql", "ddl", "tables"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result: %q", result)
+	}
+
+	expected = []string{
+		"CREATE TABLE person (person_source_value VARCHAR(50))",
+		"ALTER TABLE person ADD COLUMN site_person_id VARCHAR(64)",
+	}
+	if result := applyDDLRules(ddlRules, stmts, "pedsnet", "2.3.0", "postgresql", "ddl", "tables"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result for other version: %q", result)
+	}
+
+	if err = RegisterDDLRule(&DDLRule{Action: "mangle"}); err == nil {
+		t.Error("RegisterDDLRule accepted an invalid action")
+	}
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// TableGroup is a named subset of a data model's tables, such as the vocabulary tables. A group can be
+// selected by passing "{Model}-{Name}" (e.g. "pedsnet-vocab") as the model to Open, which then
+// operates on the group's tables of the underlying model.
+//
+// Groups can be registered in Go with RegisterTableGroup or loaded from a JSON file with LoadTableGroups. The "vocab",
+// "core" and "cdm" groups of a model are otherwise derived from its definition in the data-models-service, with the
+// default groups as a fallback (see resolveTableGroup).
+type TableGroup struct {
+	Name     string `json:"name"`     // Group name, e.g. "vocab" or "core".
+	Model    string `json:"model"`    // Model per https://github.com/chop-dbhi/data-models, e.g. "pedsnet".
+	Versions string `json:"versions"` // Range of model versions for which the group is verified, e.g. "~2.2" (see ParseVersionRange), or "" for all versions.
+	Include  string `json:"include"`  // Pattern matching the group's tables.
+	Exclude  string `json:"exclude"`  // Pattern matching the tables not in the group; used if Include is "".
+}
+
+// tableGroups holds the registered groups. Later registrations take precedence over earlier ones.
+var tableGroups []*TableGroup
+
+// Magic list of pedsnet vocabulary tables, verified for the 2.2 version series.
+var pedsnetVocabTablesPat = `^(?:vocabulary|concept|concept_ancestor|concept_class|concept_relationship|concept_synonym|domain|drug_strength|relationship|source_to_concept_map)$`
+
+// OMOP CDM v5.0 standardized vocabulary tables.
+var omopVocabTablesPat = `^(?:vocabulary|concept|concept_ancestor|concept_class|concept_relationship|concept_synonym|domain|drug_strength|relationship|source_to_concept_map|cohort_definition|attribute_definition)$`
+
+// Default groups, registered at startup. They are used when groups cannot be derived (see resolveTableGroup).
+var defaultTableGroups = []*TableGroup{
+	{Name: "vocab", Model: "pedsnet", Versions: "2.2", Include: pedsnetVocabTablesPat},
+	{Name: "core", Model: "pedsnet", Versions: "2.2", Exclude: pedsnetVocabTablesPat},
+	{Name: "vocab", Model: "omop", Versions: "5.0", Include: omopVocabTablesPat},
+	{Name: "cdm", Model: "omop", Versions: "5.0", Exclude: omopVocabTablesPat},
+}
+
+func init() {
+	for _, g := range defaultTableGroups {
+		if err := RegisterTableGroup(g); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterTableGroup adds a table group to the registry.
+func RegisterTableGroup(g *TableGroup) error {
+	if g.Name == "" || g.Model == "" {
+		return fmt.Errorf("Table group requires a name and a model")
+	}
+	if g.Include == "" && g.Exclude == "" {
+		return fmt.Errorf("Table group %s-%s requires an include or exclude pattern", g.Model, g.Name)
+	}
+	for _, pat := range []string{g.Include, g.Exclude} {
+		if _, err := regexp.Compile(pat); err != nil {
+			return fmt.Errorf("Invalid pattern '%s' in table group %s-%s: %v", pat, g.Model, g.Name, err)
+		}
+	}
+	tableGroups = append(tableGroups, g)
+	return nil
+}
+
+// LoadTableGroups registers the table groups in the JSON file `fileName`, which should contain an array of objects with the fields of TableGroup.
+func LoadTableGroups(fileName string) error {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var groups []*TableGroup
+	if err = json.Unmarshal(data, &groups); err != nil {
+		return fmt.Errorf("Error parsing table groups file `%s`: %v", fileName, err)
+	}
+	for _, g := range groups {
+		if err = RegisterTableGroup(g); err != nil {
+			return fmt.Errorf("Error in table groups file `%s`: %v", fileName, err)
+		}
+	}
+	return nil
+}
+
+// lookupTableGroup returns the registered group selected by `name` (of the form "{Model}-{Name}") for `modelVersion`, or nil if there is none.
+//
+// A group verified for `modelVersion` is preferred, in which case `verified` is true. Failing that, the most recently
+// registered group of that name is returned, although its table list may be out of date.
+func lookupTableGroup(name string, modelVersion string) (g *TableGroup, verified bool) {
+	for i := len(tableGroups) - 1; i >= 0; i-- {
+		if tableGroups[i].Model+"-"+tableGroups[i].Name != name {
+			continue
+		}
+		if tableGroups[i].Versions == "" || versionInRange(modelVersion, tableGroups[i].Versions) {
+			return tableGroups[i], true
+		}
+		if g == nil {
+			g = tableGroups[i]
+		}
+	}
+	return g, false
+}
+
+// Group names that can be derived from data-models-service metadata, and whether each includes (rather than excludes) the vocabulary tables.
+var derivableTableGroups = map[string]bool{"vocab": true, "core": false, "cdm": false}
+
+// splitDerivableGroupName splits a group name of the form "{Model}-{Name}" into model and group name, or returns "", "" if the group is not derivable.
+func splitDerivableGroupName(name string) (model string, groupName string) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return "", ""
+	}
+	if _, ok := derivableTableGroups[name[i+1:]]; !ok {
+		return "", ""
+	}
+	return name[:i], name[i+1:]
+}
+
+// baseModelName returns the model underlying `name`, which may be a table group name of the form "{Model}-{Name}".
+func baseModelName(name string) string {
+	if g, _ := lookupTableGroup(name, ""); g != nil {
+		return g.Model
+	}
+	if model, _ := splitDerivableGroupName(name); model != "" {
+		return model
+	}
+	return name
+}
+
+// deriveTableGroup builds the table group selected by `name` (of the form "{Model}-{Name}") for `modelVersion` from the
+// model's definition in the data-models-service at `dmUrl`. It returns nil if the group name is not derivable.
+func deriveTableGroup(name string, modelVersion string, dmUrl string) (*TableGroup, error) {
+	model, groupName := splitDerivableGroupName(name)
+	if model == "" {
+		return nil, nil
+	}
+	isVocab := derivableTableGroups[groupName]
+
+	m, err := FetchDataModel(dmUrl, model, modelVersion)
+	if err != nil {
+		return nil, err
+	}
+	tables := m.VocabularyTables()
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("No vocabulary tables could be identified in version %s of the %s model", modelVersion, model)
+	}
+
+	for i := range tables {
+		tables[i] = regexp.QuoteMeta(tables[i])
+	}
+	pat := fmt.Sprintf("^(?:%s)$", strings.Join(tables, "|"))
+	g := &TableGroup{Name: groupName, Model: model, Versions: modelVersion}
+	if isVocab {
+		g.Include = pat
+	} else {
+		g.Exclude = pat
+	}
+	return g, nil
+}
+
+// resolveTableGroup returns the table group selected by `name` (of the form "{Model}-{Name}") for `modelVersion`, or nil if there is none.
+//
+// A group registered with RegisterTableGroup or LoadTableGroups and verified for `modelVersion` is used if there is one.
+// Otherwise vocabulary and core groups are derived from the model's definition in the data-models-service at `dmUrl`
+// (the default service, if ""), so that they follow the tables of new model versions. The default groups, and
+// registered groups for other versions, are only used, with a warning, if the group cannot be derived, e.g. offline.
+func resolveTableGroup(name string, modelVersion string, dmUrl string) (*TableGroup, error) {
+	g, verified := lookupTableGroup(name, modelVersion)
+	if verified && !isDefaultTableGroup(g) {
+		return g, nil
+	}
+
+	if dmUrl == "" {
+		dmUrl = defaultDmUrl
+	}
+	derived, err := deriveTableGroup(name, modelVersion, dmUrl)
+	if err != nil {
+		if g == nil {
+			return nil, err
+		}
+		log.Warn(fmt.Sprintf("Cannot derive table group %s from data-models-service: %v", name, err))
+	} else if derived != nil {
+		return derived, nil
+	}
+
+	if g != nil && !verified {
+		log.WithFields(log.Fields{"VersionSupported": g.Versions}).Warn(
+			fmt.Sprintf("WARNING: table group %s is only verified for versions %s of the %s model", name, g.Versions, g.Model))
+	}
+	return g, nil
+}
+
+// isDefaultTableGroup reports whether `g` is one of defaultTableGroups.
+func isDefaultTableGroup(g *TableGroup) bool {
+	for _, d := range defaultTableGroups {
+		if g == d {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupTableGroup(t *testing.T) {
+	if g, _ := lookupTableGroup("pedsnet-vocab", "2.2.0"); g == nil || g.Model != "pedsnet" || g.Include != pedsnetVocabTablesPat {
+		t.Errorf("Unexpected group for pedsnet-vocab 2.2.0: %+v", g)
+	}
+	if g, _ := lookupTableGroup("pedsnet-core", "2.2.1"); g == nil || g.Exclude != pedsnetVocabTablesPat {
+		t.Errorf("Unexpected group for pedsnet-core 2.2.1: %+v", g)
+	}
+	if g, _ := lookupTableGroup("pedsnet", "2.2.0"); g != nil {
+		t.Errorf("Plain model name should not select a group: %+v", g)
+	}
+}
+
+func TestLoadTableGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tablegroups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "groups.json")
+	groupsJson := `[
+		{"name": "vocab", "model": "pedsnet", "versions": "2.3", "include": "^(?:concept|vocabulary|domain)$"},
+		{"name": "vocab", "model": "pcornet", "include": "^harvest$"}
+	]`
+	if err = ioutil.WriteFile(fileName, []byte(groupsJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := tableGroups
+	defer func() { tableGroups = saved }()
+
+	if err = LoadTableGroups(fileName); err != nil {
+		t.Fatalf("LoadTableGroups failed: %v", err)
+	}
+
+	if g, _ := lookupTableGroup("pedsnet-vocab", "2.3.0"); g == nil || g.Versions != "2.3" {
+		t.Errorf("Expected the 2.3 group for pedsnet-vocab 2.3.0, got %+v", g)
+	}
+	if g, _ := lookupTableGroup("pedsnet-vocab", "2.2.0"); g == nil || g.Versions != "2.2" {
+		t.Errorf("Expected the 2.2 group for pedsnet-vocab 2.2.0, got %+v", g)
+	}
+	if g, _ := lookupTableGroup("pcornet-vocab", "3.0.0"); g == nil || g.Model != "pcornet" {
+		t.Errorf("Expected a group for pcornet-vocab, got %+v", g)
+	}
+
+	if err = RegisterTableGroup(&TableGroup{Name: "broken", Model: "pedsnet", Include: "("}); err == nil {
+		t.Error("RegisterTableGroup accepted an invalid pattern")
+	}
+}
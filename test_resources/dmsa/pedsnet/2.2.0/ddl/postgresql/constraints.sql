@@ -0,0 +1,168 @@
+ALTER TABLE concept ADD CONSTRAINT fpk_concept_domain FOREIGN KEY(domain_id) REFERENCES domain (domain_id);
+
+ALTER TABLE concept ADD CONSTRAINT fpk_concept_vocabulary FOREIGN KEY(vocabulary_id) REFERENCES vocabulary (vocabulary_id);
+
+ALTER TABLE concept ADD CONSTRAINT fpk_concept_concept_class FOREIGN KEY(concept_class_id) REFERENCES concept_class (concept_class_id);
+
+ALTER TABLE vocabulary ADD CONSTRAINT fpk_vocabulary_vocabulary FOREIGN KEY(vocabulary_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE domain ADD CONSTRAINT fpk_domain_domain FOREIGN KEY(domain_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_class ADD CONSTRAINT fpk_concept_class_concept_class FOREIGN KEY(concept_class_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE relationship ADD CONSTRAINT fpk_relationship_reverse_relationship FOREIGN KEY(reverse_relationship_id) REFERENCES relationship (relationship_id);
+
+ALTER TABLE relationship ADD CONSTRAINT fpk_relationship_relationship FOREIGN KEY(relationship_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_relationship ADD CONSTRAINT fpk_concept_relationship_concept_1 FOREIGN KEY(concept_id_1) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_relationship ADD CONSTRAINT fpk_concept_relationship_concept_2 FOREIGN KEY(concept_id_2) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_relationship ADD CONSTRAINT fpk_concept_relationship_relationship FOREIGN KEY(relationship_id) REFERENCES relationship (relationship_id);
+
+ALTER TABLE concept_synonym ADD CONSTRAINT fpk_concept_synonym_concept FOREIGN KEY(concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_synonym ADD CONSTRAINT fpk_concept_synonym_language FOREIGN KEY(language_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_ancestor ADD CONSTRAINT fpk_concept_ancestor_ancestor FOREIGN KEY(ancestor_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE concept_ancestor ADD CONSTRAINT fpk_concept_ancestor_descendant FOREIGN KEY(descendant_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_strength ADD CONSTRAINT fpk_drug_strength_drug FOREIGN KEY(drug_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_strength ADD CONSTRAINT fpk_drug_strength_ingredient FOREIGN KEY(ingredient_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_strength ADD CONSTRAINT fpk_drug_strength_amount_unit FOREIGN KEY(amount_unit_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_strength ADD CONSTRAINT fpk_drug_strength_numerator_unit FOREIGN KEY(numerator_unit_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_strength ADD CONSTRAINT fpk_drug_strength_denominator_unit FOREIGN KEY(denominator_unit_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE source_to_concept_map ADD CONSTRAINT fpk_source_to_concept_map_source FOREIGN KEY(source_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE source_to_concept_map ADD CONSTRAINT fpk_source_to_concept_map_source_vocabulary FOREIGN KEY(source_vocabulary_id) REFERENCES vocabulary (vocabulary_id);
+
+ALTER TABLE source_to_concept_map ADD CONSTRAINT fpk_source_to_concept_map_target FOREIGN KEY(target_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE source_to_concept_map ADD CONSTRAINT fpk_source_to_concept_map_target_vocabulary FOREIGN KEY(target_vocabulary_id) REFERENCES vocabulary (vocabulary_id);
+
+ALTER TABLE care_site ADD CONSTRAINT fpk_care_site_place_of_service FOREIGN KEY(place_of_service_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE care_site ADD CONSTRAINT fpk_care_site_location FOREIGN KEY(location_id) REFERENCES location (location_id);
+
+ALTER TABLE care_site ADD CONSTRAINT fpk_care_site_specialty FOREIGN KEY(specialty_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE provider ADD CONSTRAINT fpk_provider_specialty FOREIGN KEY(specialty_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE provider ADD CONSTRAINT fpk_provider_care_site FOREIGN KEY(care_site_id) REFERENCES care_site (care_site_id);
+
+ALTER TABLE provider ADD CONSTRAINT fpk_provider_gender FOREIGN KEY(gender_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_gender FOREIGN KEY(gender_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_race FOREIGN KEY(race_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_ethnicity FOREIGN KEY(ethnicity_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_location FOREIGN KEY(location_id) REFERENCES location (location_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE person ADD CONSTRAINT fpk_person_care_site FOREIGN KEY(care_site_id) REFERENCES care_site (care_site_id);
+
+ALTER TABLE death ADD CONSTRAINT fpk_death_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE death ADD CONSTRAINT fpk_death_death_type FOREIGN KEY(death_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE death ADD CONSTRAINT fpk_death_cause FOREIGN KEY(cause_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE observation_period ADD CONSTRAINT fpk_observation_period_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE observation_period ADD CONSTRAINT fpk_observation_period_period_type FOREIGN KEY(period_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE visit_occurrence ADD CONSTRAINT fpk_visit_occurrence_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE visit_occurrence ADD CONSTRAINT fpk_visit_occurrence_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE visit_occurrence ADD CONSTRAINT fpk_visit_occurrence_care_site FOREIGN KEY(care_site_id) REFERENCES care_site (care_site_id);
+
+ALTER TABLE visit_occurrence ADD CONSTRAINT fpk_visit_occurrence_visit FOREIGN KEY(visit_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE visit_occurrence ADD CONSTRAINT fpk_visit_occurrence_visit_type FOREIGN KEY(visit_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE visit_payer ADD CONSTRAINT fpk_visit_payer_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE visit_payer ADD CONSTRAINT fpk_visit_payer_plan_class FOREIGN KEY(plan_class_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE visit_payer ADD CONSTRAINT fpk_visit_payer_plan_type FOREIGN KEY(plan_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE condition_occurrence ADD CONSTRAINT fpk_condition_occurrence_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE condition_occurrence ADD CONSTRAINT fpk_condition_occurrence_condition FOREIGN KEY(condition_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE condition_occurrence ADD CONSTRAINT fpk_condition_occurrence_condition_type FOREIGN KEY(condition_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE condition_occurrence ADD CONSTRAINT fpk_condition_occurrence_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE condition_occurrence ADD CONSTRAINT fpk_condition_occurrence_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE procedure_occurrence ADD CONSTRAINT fpk_procedure_occurrence_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE procedure_occurrence ADD CONSTRAINT fpk_procedure_occurrence_procedure FOREIGN KEY(procedure_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE procedure_occurrence ADD CONSTRAINT fpk_procedure_occurrence_procedure_type FOREIGN KEY(procedure_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE procedure_occurrence ADD CONSTRAINT fpk_procedure_occurrence_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE procedure_occurrence ADD CONSTRAINT fpk_procedure_occurrence_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE drug_exposure ADD CONSTRAINT fpk_drug_exposure_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE drug_exposure ADD CONSTRAINT fpk_drug_exposure_drug FOREIGN KEY(drug_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_exposure ADD CONSTRAINT fpk_drug_exposure_drug_type FOREIGN KEY(drug_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE drug_exposure ADD CONSTRAINT fpk_drug_exposure_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE drug_exposure ADD CONSTRAINT fpk_drug_exposure_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_measurement FOREIGN KEY(measurement_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_measurement_type FOREIGN KEY(measurement_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_value_as FOREIGN KEY(value_as_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_unit FOREIGN KEY(unit_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE measurement ADD CONSTRAINT fpk_measurement_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE measurement_organism ADD CONSTRAINT fpk_measurement_organism_measurement FOREIGN KEY(measurement_id) REFERENCES measurement (measurement_id);
+
+ALTER TABLE measurement_organism ADD CONSTRAINT fpk_measurement_organism_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE measurement_organism ADD CONSTRAINT fpk_measurement_organism_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE measurement_organism ADD CONSTRAINT fpk_measurement_organism_organism FOREIGN KEY(organism_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_person FOREIGN KEY(person_id) REFERENCES person (person_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_observation FOREIGN KEY(observation_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_observation_type FOREIGN KEY(observation_type_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_value_as FOREIGN KEY(value_as_concept_id) REFERENCES concept (concept_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_provider FOREIGN KEY(provider_id) REFERENCES provider (provider_id);
+
+ALTER TABLE observation ADD CONSTRAINT fpk_observation_visit_occurrence FOREIGN KEY(visit_occurrence_id) REFERENCES visit_occurrence (visit_occurrence_id);
+
+ALTER TABLE fact_relationship ADD CONSTRAINT fpk_fact_relationship_domain_1 FOREIGN KEY(domain_concept_id_1) REFERENCES concept (concept_id);
+
+ALTER TABLE fact_relationship ADD CONSTRAINT fpk_fact_relationship_domain_2 FOREIGN KEY(domain_concept_id_2) REFERENCES concept (concept_id);
+
+ALTER TABLE fact_relationship ADD CONSTRAINT fpk_fact_relationship_relationship FOREIGN KEY(relationship_concept_id) REFERENCES concept (concept_id);
+
@@ -0,0 +1,12 @@
+CREATE INDEX idx_concept_code ON concept (concept_code);
+
+CREATE INDEX idx_concept_vocabulary_id ON concept (vocabulary_id);
+
+CREATE INDEX idx_person_source_value ON person (person_source_value);
+
+CREATE INDEX idx_visit_person ON visit_occurrence (person_id);
+
+CREATE INDEX idx_measurement_person ON measurement (person_id);
+
+CREATE INDEX idx_concept_synonym_concept ON concept_synonym (concept_id);
+
@@ -0,0 +1,297 @@
+CREATE TABLE concept (
+	concept_id INTEGER NOT NULL, 
+	concept_name VARCHAR(255) NOT NULL, 
+	domain_id VARCHAR(20) NOT NULL, 
+	vocabulary_id VARCHAR(20) NOT NULL, 
+	concept_class_id VARCHAR(20) NOT NULL, 
+	standard_concept VARCHAR(1), 
+	concept_code VARCHAR(50) NOT NULL, 
+	valid_start_date DATE NOT NULL, 
+	valid_end_date DATE NOT NULL, 
+	invalid_reason VARCHAR(1), 
+	CONSTRAINT xpk_concept PRIMARY KEY (concept_id)
+);
+
+CREATE TABLE vocabulary (
+	vocabulary_id VARCHAR(20) NOT NULL, 
+	vocabulary_name VARCHAR(255) NOT NULL, 
+	vocabulary_reference VARCHAR(255) NOT NULL, 
+	vocabulary_version VARCHAR(255), 
+	vocabulary_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_vocabulary PRIMARY KEY (vocabulary_id)
+);
+
+CREATE TABLE domain (
+	domain_id VARCHAR(20) NOT NULL, 
+	domain_name VARCHAR(255) NOT NULL, 
+	domain_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_domain PRIMARY KEY (domain_id)
+);
+
+CREATE TABLE concept_class (
+	concept_class_id VARCHAR(20) NOT NULL, 
+	concept_class_name VARCHAR(255) NOT NULL, 
+	concept_class_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_concept_class PRIMARY KEY (concept_class_id)
+);
+
+CREATE TABLE relationship (
+	relationship_id VARCHAR(20) NOT NULL, 
+	relationship_name VARCHAR(255) NOT NULL, 
+	is_hierarchical VARCHAR(1) NOT NULL, 
+	defines_ancestry VARCHAR(1) NOT NULL, 
+	reverse_relationship_id VARCHAR(20) NOT NULL, 
+	relationship_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_relationship PRIMARY KEY (relationship_id)
+);
+
+CREATE TABLE concept_relationship (
+	concept_id_1 INTEGER NOT NULL, 
+	concept_id_2 INTEGER NOT NULL, 
+	relationship_id VARCHAR(20) NOT NULL, 
+	valid_start_date DATE NOT NULL, 
+	valid_end_date DATE NOT NULL, 
+	invalid_reason VARCHAR(1), 
+	CONSTRAINT xpk_concept_relationship PRIMARY KEY (concept_id_1, concept_id_2, relationship_id)
+);
+
+CREATE TABLE concept_synonym (
+	concept_id INTEGER NOT NULL, 
+	concept_synonym_name VARCHAR(1000) NOT NULL, 
+	language_concept_id INTEGER NOT NULL
+);
+
+CREATE TABLE concept_ancestor (
+	ancestor_concept_id INTEGER NOT NULL, 
+	descendant_concept_id INTEGER NOT NULL, 
+	min_levels_of_separation INTEGER NOT NULL, 
+	max_levels_of_separation INTEGER NOT NULL, 
+	CONSTRAINT xpk_concept_ancestor PRIMARY KEY (ancestor_concept_id, descendant_concept_id)
+);
+
+CREATE TABLE drug_strength (
+	drug_concept_id INTEGER NOT NULL, 
+	ingredient_concept_id INTEGER NOT NULL, 
+	amount_value NUMERIC(50, 10), 
+	amount_unit_concept_id INTEGER, 
+	numerator_value NUMERIC(50, 10), 
+	numerator_unit_concept_id INTEGER, 
+	denominator_value NUMERIC(50, 10), 
+	denominator_unit_concept_id INTEGER, 
+	valid_start_date DATE NOT NULL, 
+	valid_end_date DATE NOT NULL, 
+	invalid_reason VARCHAR(1), 
+	CONSTRAINT xpk_drug_strength PRIMARY KEY (drug_concept_id, ingredient_concept_id)
+);
+
+CREATE TABLE source_to_concept_map (
+	source_code VARCHAR(50) NOT NULL, 
+	source_concept_id INTEGER NOT NULL, 
+	source_vocabulary_id VARCHAR(20) NOT NULL, 
+	source_code_description VARCHAR(255), 
+	target_concept_id INTEGER NOT NULL, 
+	target_vocabulary_id VARCHAR(20) NOT NULL, 
+	valid_start_date DATE NOT NULL, 
+	valid_end_date DATE NOT NULL, 
+	invalid_reason VARCHAR(1), 
+	CONSTRAINT xpk_source_to_concept_map PRIMARY KEY (source_vocabulary_id, target_concept_id, source_code, valid_end_date)
+);
+
+CREATE TABLE location (
+	location_id INTEGER NOT NULL, 
+	address_1 VARCHAR(50), 
+	address_2 VARCHAR(50), 
+	city VARCHAR(50), 
+	state VARCHAR(2), 
+	zip VARCHAR(9), 
+	county VARCHAR(20), 
+	location_source_value VARCHAR(256), 
+	CONSTRAINT xpk_location PRIMARY KEY (location_id)
+);
+
+CREATE TABLE care_site (
+	care_site_id INTEGER NOT NULL, 
+	care_site_name VARCHAR(255), 
+	place_of_service_concept_id INTEGER NOT NULL, 
+	location_id INTEGER, 
+	care_site_source_value VARCHAR(256) NOT NULL, 
+	place_of_service_source_value VARCHAR(256), 
+	specialty_concept_id INTEGER, 
+	specialty_source_value VARCHAR(256), 
+	CONSTRAINT xpk_care_site PRIMARY KEY (care_site_id)
+);
+
+CREATE TABLE provider (
+	provider_id INTEGER NOT NULL, 
+	provider_name VARCHAR(255), 
+	specialty_concept_id INTEGER, 
+	care_site_id INTEGER, 
+	year_of_birth INTEGER, 
+	gender_concept_id INTEGER, 
+	provider_source_value VARCHAR(256) NOT NULL, 
+	specialty_source_value VARCHAR(256), 
+	gender_source_value VARCHAR(256), 
+	CONSTRAINT xpk_provider PRIMARY KEY (provider_id)
+);
+
+CREATE TABLE person (
+	person_id INTEGER NOT NULL, 
+	gender_concept_id INTEGER NOT NULL, 
+	year_of_birth INTEGER NOT NULL, 
+	month_of_birth INTEGER, 
+	day_of_birth INTEGER, 
+	time_of_birth TIMESTAMP WITHOUT TIME ZONE, 
+	race_concept_id INTEGER NOT NULL, 
+	ethnicity_concept_id INTEGER NOT NULL, 
+	location_id INTEGER, 
+	provider_id INTEGER, 
+	care_site_id INTEGER NOT NULL, 
+	person_source_value VARCHAR(256) NOT NULL, 
+	gender_source_value VARCHAR(256), 
+	race_source_value VARCHAR(256), 
+	ethnicity_source_value VARCHAR(256), 
+	pn_gestational_age NUMERIC(4, 2), 
+	CONSTRAINT xpk_person PRIMARY KEY (person_id)
+);
+
+CREATE TABLE death (
+	person_id INTEGER NOT NULL, 
+	death_date DATE NOT NULL, 
+	death_time TIMESTAMP WITHOUT TIME ZONE, 
+	death_type_concept_id INTEGER NOT NULL, 
+	cause_concept_id INTEGER, 
+	cause_source_value VARCHAR(256), 
+	CONSTRAINT xpk_death PRIMARY KEY (person_id)
+);
+
+CREATE TABLE observation_period (
+	observation_period_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	observation_period_start_date DATE NOT NULL, 
+	observation_period_end_date DATE, 
+	period_type_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_observation_period PRIMARY KEY (observation_period_id)
+);
+
+CREATE TABLE visit_occurrence (
+	visit_occurrence_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	visit_start_date DATE NOT NULL, 
+	visit_start_time TIMESTAMP WITHOUT TIME ZONE, 
+	visit_end_date DATE, 
+	visit_end_time TIMESTAMP WITHOUT TIME ZONE, 
+	provider_id INTEGER, 
+	care_site_id INTEGER, 
+	visit_concept_id INTEGER NOT NULL, 
+	visit_type_concept_id INTEGER NOT NULL, 
+	visit_source_value VARCHAR(256), 
+	CONSTRAINT xpk_visit_occurrence PRIMARY KEY (visit_occurrence_id)
+);
+
+CREATE TABLE visit_payer (
+	visit_payer_id INTEGER NOT NULL, 
+	visit_occurrence_id INTEGER NOT NULL, 
+	plan_name VARCHAR(255) NOT NULL, 
+	plan_class_concept_id INTEGER NOT NULL, 
+	plan_type_concept_id INTEGER NOT NULL, 
+	CONSTRAINT xpk_visit_payer PRIMARY KEY (visit_payer_id)
+);
+
+CREATE TABLE condition_occurrence (
+	condition_occurrence_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	condition_concept_id INTEGER NOT NULL, 
+	condition_start_date DATE NOT NULL, 
+	condition_end_date DATE, 
+	condition_type_concept_id INTEGER NOT NULL, 
+	provider_id INTEGER, 
+	visit_occurrence_id INTEGER, 
+	condition_source_value VARCHAR(256) NOT NULL, 
+	CONSTRAINT xpk_condition_occurrence PRIMARY KEY (condition_occurrence_id)
+);
+
+CREATE TABLE procedure_occurrence (
+	procedure_occurrence_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	procedure_concept_id INTEGER NOT NULL, 
+	procedure_date DATE NOT NULL, 
+	procedure_type_concept_id INTEGER NOT NULL, 
+	provider_id INTEGER, 
+	visit_occurrence_id INTEGER, 
+	procedure_source_value VARCHAR(256) NOT NULL, 
+	CONSTRAINT xpk_procedure_occurrence PRIMARY KEY (procedure_occurrence_id)
+);
+
+CREATE TABLE drug_exposure (
+	drug_exposure_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	drug_concept_id INTEGER NOT NULL, 
+	drug_exposure_start_date DATE NOT NULL, 
+	drug_exposure_end_date DATE, 
+	drug_type_concept_id INTEGER NOT NULL, 
+	quantity NUMERIC(20, 5), 
+	provider_id INTEGER, 
+	visit_occurrence_id INTEGER, 
+	drug_source_value VARCHAR(256), 
+	CONSTRAINT xpk_drug_exposure PRIMARY KEY (drug_exposure_id)
+);
+
+CREATE TABLE measurement (
+	measurement_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	measurement_concept_id INTEGER NOT NULL, 
+	measurement_date DATE NOT NULL, 
+	measurement_time TIMESTAMP WITHOUT TIME ZONE, 
+	measurement_type_concept_id INTEGER NOT NULL, 
+	value_as_number NUMERIC(20, 5), 
+	value_as_concept_id INTEGER, 
+	unit_concept_id INTEGER, 
+	provider_id INTEGER, 
+	visit_occurrence_id INTEGER, 
+	measurement_source_value VARCHAR(256), 
+	CONSTRAINT xpk_measurement PRIMARY KEY (measurement_id)
+);
+
+CREATE TABLE measurement_organism (
+	meas_organism_id INTEGER NOT NULL, 
+	measurement_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	visit_occurrence_id INTEGER, 
+	organism_concept_id INTEGER NOT NULL, 
+	organism_source_value VARCHAR(256), 
+	CONSTRAINT xpk_measurement_organism PRIMARY KEY (meas_organism_id)
+);
+
+CREATE TABLE observation (
+	observation_id INTEGER NOT NULL, 
+	person_id INTEGER NOT NULL, 
+	observation_concept_id INTEGER NOT NULL, 
+	observation_date DATE NOT NULL, 
+	observation_time TIMESTAMP WITHOUT TIME ZONE, 
+	observation_type_concept_id INTEGER NOT NULL, 
+	value_as_number NUMERIC(20, 5), 
+	value_as_string VARCHAR(256), 
+	value_as_concept_id INTEGER, 
+	provider_id INTEGER, 
+	visit_occurrence_id INTEGER, 
+	observation_source_value VARCHAR(256), 
+	CONSTRAINT xpk_observation PRIMARY KEY (observation_id)
+);
+
+CREATE TABLE fact_relationship (
+	domain_concept_id_1 INTEGER NOT NULL, 
+	fact_id_1 INTEGER NOT NULL, 
+	domain_concept_id_2 INTEGER NOT NULL, 
+	fact_id_2 INTEGER NOT NULL, 
+	relationship_concept_id INTEGER NOT NULL
+);
+
+CREATE TABLE version_history (
+	operation VARCHAR(100), 
+	model VARCHAR(16) NOT NULL, 
+	model_version VARCHAR(50) NOT NULL, 
+	dms_version VARCHAR(16), 
+	dmsa_version VARCHAR(50), 
+	datetime TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
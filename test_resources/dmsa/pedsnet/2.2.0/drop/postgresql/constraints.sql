@@ -0,0 +1,168 @@
+ALTER TABLE fact_relationship DROP CONSTRAINT fpk_fact_relationship_relationship;
+
+ALTER TABLE fact_relationship DROP CONSTRAINT fpk_fact_relationship_domain_2;
+
+ALTER TABLE fact_relationship DROP CONSTRAINT fpk_fact_relationship_domain_1;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_visit_occurrence;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_provider;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_value_as;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_observation_type;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_observation;
+
+ALTER TABLE observation DROP CONSTRAINT fpk_observation_person;
+
+ALTER TABLE measurement_organism DROP CONSTRAINT fpk_measurement_organism_organism;
+
+ALTER TABLE measurement_organism DROP CONSTRAINT fpk_measurement_organism_visit_occurrence;
+
+ALTER TABLE measurement_organism DROP CONSTRAINT fpk_measurement_organism_person;
+
+ALTER TABLE measurement_organism DROP CONSTRAINT fpk_measurement_organism_measurement;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_visit_occurrence;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_provider;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_unit;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_value_as;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_measurement_type;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_measurement;
+
+ALTER TABLE measurement DROP CONSTRAINT fpk_measurement_person;
+
+ALTER TABLE drug_exposure DROP CONSTRAINT fpk_drug_exposure_visit_occurrence;
+
+ALTER TABLE drug_exposure DROP CONSTRAINT fpk_drug_exposure_provider;
+
+ALTER TABLE drug_exposure DROP CONSTRAINT fpk_drug_exposure_drug_type;
+
+ALTER TABLE drug_exposure DROP CONSTRAINT fpk_drug_exposure_drug;
+
+ALTER TABLE drug_exposure DROP CONSTRAINT fpk_drug_exposure_person;
+
+ALTER TABLE procedure_occurrence DROP CONSTRAINT fpk_procedure_occurrence_visit_occurrence;
+
+ALTER TABLE procedure_occurrence DROP CONSTRAINT fpk_procedure_occurrence_provider;
+
+ALTER TABLE procedure_occurrence DROP CONSTRAINT fpk_procedure_occurrence_procedure_type;
+
+ALTER TABLE procedure_occurrence DROP CONSTRAINT fpk_procedure_occurrence_procedure;
+
+ALTER TABLE procedure_occurrence DROP CONSTRAINT fpk_procedure_occurrence_person;
+
+ALTER TABLE condition_occurrence DROP CONSTRAINT fpk_condition_occurrence_visit_occurrence;
+
+ALTER TABLE condition_occurrence DROP CONSTRAINT fpk_condition_occurrence_provider;
+
+ALTER TABLE condition_occurrence DROP CONSTRAINT fpk_condition_occurrence_condition_type;
+
+ALTER TABLE condition_occurrence DROP CONSTRAINT fpk_condition_occurrence_condition;
+
+ALTER TABLE condition_occurrence DROP CONSTRAINT fpk_condition_occurrence_person;
+
+ALTER TABLE visit_payer DROP CONSTRAINT fpk_visit_payer_plan_type;
+
+ALTER TABLE visit_payer DROP CONSTRAINT fpk_visit_payer_plan_class;
+
+ALTER TABLE visit_payer DROP CONSTRAINT fpk_visit_payer_visit_occurrence;
+
+ALTER TABLE visit_occurrence DROP CONSTRAINT fpk_visit_occurrence_visit_type;
+
+ALTER TABLE visit_occurrence DROP CONSTRAINT fpk_visit_occurrence_visit;
+
+ALTER TABLE visit_occurrence DROP CONSTRAINT fpk_visit_occurrence_care_site;
+
+ALTER TABLE visit_occurrence DROP CONSTRAINT fpk_visit_occurrence_provider;
+
+ALTER TABLE visit_occurrence DROP CONSTRAINT fpk_visit_occurrence_person;
+
+ALTER TABLE observation_period DROP CONSTRAINT fpk_observation_period_period_type;
+
+ALTER TABLE observation_period DROP CONSTRAINT fpk_observation_period_person;
+
+ALTER TABLE death DROP CONSTRAINT fpk_death_cause;
+
+ALTER TABLE death DROP CONSTRAINT fpk_death_death_type;
+
+ALTER TABLE death DROP CONSTRAINT fpk_death_person;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_care_site;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_provider;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_location;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_ethnicity;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_race;
+
+ALTER TABLE person DROP CONSTRAINT fpk_person_gender;
+
+ALTER TABLE provider DROP CONSTRAINT fpk_provider_gender;
+
+ALTER TABLE provider DROP CONSTRAINT fpk_provider_care_site;
+
+ALTER TABLE provider DROP CONSTRAINT fpk_provider_specialty;
+
+ALTER TABLE care_site DROP CONSTRAINT fpk_care_site_specialty;
+
+ALTER TABLE care_site DROP CONSTRAINT fpk_care_site_location;
+
+ALTER TABLE care_site DROP CONSTRAINT fpk_care_site_place_of_service;
+
+ALTER TABLE source_to_concept_map DROP CONSTRAINT fpk_source_to_concept_map_target_vocabulary;
+
+ALTER TABLE source_to_concept_map DROP CONSTRAINT fpk_source_to_concept_map_target;
+
+ALTER TABLE source_to_concept_map DROP CONSTRAINT fpk_source_to_concept_map_source_vocabulary;
+
+ALTER TABLE source_to_concept_map DROP CONSTRAINT fpk_source_to_concept_map_source;
+
+ALTER TABLE drug_strength DROP CONSTRAINT fpk_drug_strength_denominator_unit;
+
+ALTER TABLE drug_strength DROP CONSTRAINT fpk_drug_strength_numerator_unit;
+
+ALTER TABLE drug_strength DROP CONSTRAINT fpk_drug_strength_amount_unit;
+
+ALTER TABLE drug_strength DROP CONSTRAINT fpk_drug_strength_ingredient;
+
+ALTER TABLE drug_strength DROP CONSTRAINT fpk_drug_strength_drug;
+
+ALTER TABLE concept_ancestor DROP CONSTRAINT fpk_concept_ancestor_descendant;
+
+ALTER TABLE concept_ancestor DROP CONSTRAINT fpk_concept_ancestor_ancestor;
+
+ALTER TABLE concept_synonym DROP CONSTRAINT fpk_concept_synonym_language;
+
+ALTER TABLE concept_synonym DROP CONSTRAINT fpk_concept_synonym_concept;
+
+ALTER TABLE concept_relationship DROP CONSTRAINT fpk_concept_relationship_relationship;
+
+ALTER TABLE concept_relationship DROP CONSTRAINT fpk_concept_relationship_concept_2;
+
+ALTER TABLE concept_relationship DROP CONSTRAINT fpk_concept_relationship_concept_1;
+
+ALTER TABLE relationship DROP CONSTRAINT fpk_relationship_relationship;
+
+ALTER TABLE relationship DROP CONSTRAINT fpk_relationship_reverse_relationship;
+
+ALTER TABLE concept_class DROP CONSTRAINT fpk_concept_class_concept_class;
+
+ALTER TABLE domain DROP CONSTRAINT fpk_domain_domain;
+
+ALTER TABLE vocabulary DROP CONSTRAINT fpk_vocabulary_vocabulary;
+
+ALTER TABLE concept DROP CONSTRAINT fpk_concept_concept_class;
+
+ALTER TABLE concept DROP CONSTRAINT fpk_concept_vocabulary;
+
+ALTER TABLE concept DROP CONSTRAINT fpk_concept_domain;
+
@@ -0,0 +1,12 @@
+DROP INDEX idx_concept_synonym_concept;
+
+DROP INDEX idx_measurement_person;
+
+DROP INDEX idx_visit_person;
+
+DROP INDEX idx_person_source_value;
+
+DROP INDEX idx_concept_vocabulary_id;
+
+DROP INDEX idx_concept_code;
+
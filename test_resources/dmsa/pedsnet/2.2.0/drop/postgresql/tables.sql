@@ -0,0 +1,52 @@
+DROP TABLE version_history;
+
+DROP TABLE fact_relationship;
+
+DROP TABLE observation;
+
+DROP TABLE measurement_organism;
+
+DROP TABLE measurement;
+
+DROP TABLE drug_exposure;
+
+DROP TABLE procedure_occurrence;
+
+DROP TABLE condition_occurrence;
+
+DROP TABLE visit_payer;
+
+DROP TABLE visit_occurrence;
+
+DROP TABLE observation_period;
+
+DROP TABLE death;
+
+DROP TABLE person;
+
+DROP TABLE provider;
+
+DROP TABLE care_site;
+
+DROP TABLE location;
+
+DROP TABLE source_to_concept_map;
+
+DROP TABLE drug_strength;
+
+DROP TABLE concept_ancestor;
+
+DROP TABLE concept_synonym;
+
+DROP TABLE concept_relationship;
+
+DROP TABLE relationship;
+
+DROP TABLE concept_class;
+
+DROP TABLE domain;
+
+DROP TABLE vocabulary;
+
+DROP TABLE concept;
+
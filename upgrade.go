@@ -0,0 +1,269 @@
+package database
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"sort"
+	"strings"
+)
+
+// Patterns for parsing index and constraint creation SQL, for the purpose of upgrades.
+var upgradeIndexPatterns = mapPatternsType{tableCreate: ` ON (\w+) \(`, entityCreate: `CREATE (?:UNIQUE )?INDEX (\w+) ON`}
+var upgradeConstraintPatterns = mapPatternsType{tableCreate: `ALTER TABLE (\w+)`, entityCreate: `ADD CONSTRAINT (\w+)`}
+
+// modelDefinition holds the parsed tables, indexes and constraints for one version of a data model.
+type modelDefinition struct {
+	tables      map[string]*tableDefinition
+	indexes     map[string]*entityDefinition
+	constraints map[string]*entityDefinition
+}
+
+// fetchModelDefinition fetches and parses the creation DDL for version `modelVersion` of `d.Model`.
+// Tables not selected by the includeTables and excludeTables patterns, and the `version_history` table, are omitted.
+func fetchModelDefinition(d *Database, modelVersion string) (*modelDefinition, error) {
+	var (
+		m     = new(modelDefinition)
+		stmts []string
+		err   error
+	)
+
+	if stmts, err = rawDmsaSql(d, modelVersion, "ddl", "tables"); err != nil {
+		return nil, err
+	}
+	if m.tables, err = parseTableDefinitions(stmts); err != nil {
+		return nil, err
+	}
+
+	if stmts, err = rawDmsaSql(d, modelVersion, "ddl", "indexes"); err != nil {
+		return nil, err
+	}
+	if m.indexes, err = parseEntityDefinitions(stmts, upgradeIndexPatterns); err != nil {
+		return nil, err
+	}
+
+	if stmts, err = rawDmsaSql(d, modelVersion, "ddl", "constraints"); err != nil {
+		return nil, err
+	}
+	if m.constraints, err = parseEntityDefinitions(stmts, upgradeConstraintPatterns); err != nil {
+		return nil, err
+	}
+
+	m.filter(func(table string) bool {
+		return table != "version_history" && d.isTableSelected(table)
+	})
+	return m, nil
+}
+
+// filter removes tables, and the indexes and constraints on them, for which `keep` returns false.
+func (m *modelDefinition) filter(keep func(table string) bool) {
+	for name := range m.tables {
+		if !keep(name) {
+			delete(m.tables, name)
+		}
+	}
+	for _, entities := range []map[string]*entityDefinition{m.indexes, m.constraints} {
+		for name, e := range entities {
+			if !keep(e.table) {
+				delete(entities, name)
+			}
+		}
+	}
+}
+
+// changedEntities returns the names of entities in `from` that are absent from, or differ in, `to`.
+func changedEntities(from map[string]*entityDefinition, to map[string]*entityDefinition) []string {
+	var names []string
+	for name, e := range from {
+		if other, ok := to[name]; !ok || normalizeSql(other.sql) != normalizeSql(e.sql) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tableClauseDropSql returns SQL dropping a table-level clause parsed from a CREATE TABLE statement, or "" if the clause cannot be dropped by name.
+func tableClauseDropSql(table string, clause string) string {
+	upper := strings.ToUpper(clause)
+	if strings.HasPrefix(upper, "PRIMARY KEY") {
+		// PostgreSQL's default name for an unnamed primary key constraint
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s_pkey", table, table)
+	}
+	if strings.HasPrefix(upper, "CONSTRAINT ") {
+		if fields := strings.Fields(clause); len(fields) > 1 {
+			return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, fields[1])
+		}
+	}
+	return ""
+}
+
+// alterTableSql returns the SQL needed to change table `from` into table `to`: added, dropped and altered columns, and table-level clauses.
+//
+// A NOT NULL column without a default is added as nullable, since the table's existing rows have no values for it, with
+// a warning that it must be populated and made NOT NULL by hand.
+func alterTableSql(from *tableDefinition, to *tableDefinition) []string {
+	var stmts []string
+
+	fromClauses := make(map[string]bool)
+	for _, clause := range from.constraints {
+		fromClauses[clause] = true
+	}
+	toClauses := make(map[string]bool)
+	for _, clause := range to.constraints {
+		toClauses[clause] = true
+	}
+
+	for _, clause := range from.constraints {
+		if !toClauses[clause] {
+			if stmt := tableClauseDropSql(from.name, clause); stmt != "" {
+				stmts = append(stmts, stmt)
+			} else {
+				log.Warn(fmt.Sprintf("Cannot drop `%s` from table %s automatically; it must be dropped by hand", clause, from.name))
+			}
+		}
+	}
+
+	for _, c := range from.columns {
+		if to.column(c.name) == nil {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", from.name, c.name))
+		}
+	}
+
+	for _, c := range to.columns {
+		old := from.column(c.name)
+		if old == nil && c.notNull && c.defaultValue == "" {
+			nullable := *c
+			nullable.notNull = false
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", to.name, nullable.definition()))
+			log.Warn(fmt.Sprintf("Column %s.%s is added as nullable, since existing rows have no value for it; populate it, then run `ALTER TABLE %s ALTER COLUMN %s SET NOT NULL`", to.name, c.name, to.name, c.name))
+			continue
+		} else if old == nil {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", to.name, c.definition()))
+			continue
+		}
+		if normalizeSql(old.sqlType) != normalizeSql(c.sqlType) {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", to.name, c.name, c.sqlType))
+		}
+		if normalizeSql(old.defaultValue) != normalizeSql(c.defaultValue) {
+			if c.defaultValue == "" {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", to.name, c.name))
+			} else {
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", to.name, c.name, c.defaultValue))
+			}
+		}
+		if old.notNull && !c.notNull {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", to.name, c.name))
+		} else if !old.notNull && c.notNull {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", to.name, c.name))
+		}
+	}
+
+	for _, clause := range to.constraints {
+		if !fromClauses[clause] {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD %s", to.name, clause))
+		}
+	}
+
+	return stmts
+}
+
+// upgradeSql computes the SQL statements needed to change a database from model definition `from` to `to`.
+//
+// Statements are ordered so that dependent objects are removed before the objects they depend on:
+// changed or removed constraints and indexes are dropped first, then tables are created and altered,
+// then removed tables are dropped, and finally new or changed indexes and constraints are created.
+func upgradeSql(from *modelDefinition, to *modelDefinition) []string {
+	var stmts []string
+
+	for _, name := range changedEntities(from.constraints, to.constraints) {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", from.constraints[name].table, name))
+	}
+	for _, name := range changedEntities(from.indexes, to.indexes) {
+		stmts = append(stmts, fmt.Sprintf("DROP INDEX %s", name))
+	}
+
+	var tableNames []string
+	for name := range to.tables {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+	for _, name := range tableNames {
+		if old, ok := from.tables[name]; ok {
+			stmts = append(stmts, alterTableSql(old, to.tables[name])...)
+		} else {
+			stmts = append(stmts, to.tables[name].sql)
+		}
+	}
+
+	tableNames = nil
+	for name := range from.tables {
+		if _, ok := to.tables[name]; !ok {
+			tableNames = append(tableNames, name)
+		}
+	}
+	sort.Strings(tableNames)
+	for _, name := range tableNames {
+		stmts = append(stmts, fmt.Sprintf("DROP TABLE %s", name))
+	}
+
+	for _, name := range changedEntities(to.indexes, from.indexes) {
+		stmts = append(stmts, to.indexes[name].sql)
+	}
+	for _, name := range changedEntities(to.constraints, from.constraints) {
+		stmts = append(stmts, to.constraints[name].sql)
+	}
+
+	return stmts
+}
+
+// UpgradeSql returns the SQL statements that upgrade the data model tables, indexes and constraints
+// from `d.ModelVersion` to `targetVersion`. The statements are computed by comparing the
+// data-models-sqlalchemy creation DDL for the two versions, honoring the includeTables and
+// excludeTables patterns. Nothing is executed. New NOT NULL columns without defaults are added as nullable (see
+// alterTableSql), and column-level REFERENCES and CHECK clauses are not compared (see parseColumnDefinition).
+func (d *Database) UpgradeSql(targetVersion string) ([]string, error) {
+	current, err := ParseModelVersion(d.ModelVersion)
+	if err != nil {
+		return nil, err
+	}
+	target, err := ParseModelVersion(targetVersion)
+	if err != nil {
+		return nil, err
+	}
+	if target.Compare(current) == 0 {
+		return nil, fmt.Errorf("Model %s is already at version %s", d.Model, d.ModelVersion)
+	} else if target.Less(current) {
+		log.Warn(fmt.Sprintf("Version %s of model %s is older than %s; computing a downgrade", targetVersion, d.Model, d.ModelVersion))
+	}
+
+	from, err := fetchModelDefinition(d, d.ModelVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fetchModelDefinition(d, targetVersion)
+	if err != nil {
+		return nil, err
+	}
+	return upgradeSql(from, to), nil
+}
+
+// Upgrade upgrades the data model tables, indexes and constraints from `d.ModelVersion` to
+// `targetVersion` by executing the statements returned by UpgradeSql, and records the upgrade in the
+// `version_history` table. Once the statements have executed, `d.ModelVersion` is set to `targetVersion`.
+//
+// `errorMode` is "normal", "strict" or "force", as for CreateTables.
+func (d *Database) Upgrade(targetVersion string, errorMode string) error {
+	stmts, err := d.UpgradeSql(targetVersion)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Upgrading %s from %s to %s: %d statements", d.Model, d.ModelVersion, targetVersion, len(stmts)))
+
+	if err = executeStatements(d.executor(), stmts, errorMode, fmt.Sprintf("upgrade from %s to %s", d.ModelVersion, targetVersion)); err != nil {
+		return err
+	}
+
+	fromVersion := d.ModelVersion
+	d.ModelVersion = targetVersion
+	return d.recordOperation(fmt.Sprintf("upgrade from %s", fromVersion), errorMode)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+// upgradeToTables is the table DDL that upgrades of the fixture model (see fixtureTables) are tested against.
+var upgradeToTables = []string{`
+CREATE TABLE person (
+	person_id INTEGER NOT NULL,
+	gender_concept_id INTEGER,
+	year_of_birth INTEGER DEFAULT 0 NOT NULL,
+	person_source_value VARCHAR(256),
+	language_concept_id INTEGER NOT NULL,
+	race_concept_id INTEGER DEFAULT 0 NOT NULL,
+	PRIMARY KEY (person_id)
+)`, `
+CREATE TABLE death (
+	person_id INTEGER NOT NULL,
+	death_date DATE NOT NULL
+)`}
+
+var upgradeFromIndexes = []string{
+	"CREATE INDEX idx_person_gender ON person (gender_concept_id)",
+	"CREATE INDEX idx_person_source ON person (person_source_value)",
+	"CREATE INDEX idx_visit_payer_plan ON visit_payer (plan_name)",
+}
+
+var upgradeToIndexes = []string{
+	"CREATE INDEX idx_person_gender ON person (gender_concept_id)",
+	"CREATE INDEX idx_person_source ON person (person_source_value, person_id)",
+	"CREATE INDEX idx_death_person ON death (person_id)",
+}
+
+var upgradeToConstraints = []string{
+	"ALTER TABLE death ADD CONSTRAINT fpk_death_person FOREIGN KEY(person_id) REFERENCES person (person_id)",
+}
+
+func TestParseTableDefinitions(t *testing.T) {
+	tables, err := parseTableDefinitions(fixtureTables)
+	if err != nil {
+		t.Fatalf("parseTableDefinitions failed: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(tables))
+	}
+	person := tables["person"]
+	if len(person.columns) != 5 {
+		t.Fatalf("Expected 5 person columns, got %d", len(person.columns))
+	}
+	c := person.column("pn_gestational_age")
+	if c == nil || c.sqlType != "NUMERIC(4, 2)" || c.notNull {
+		t.Errorf("pn_gestational_age parsed incorrectly: %+v", c)
+	}
+	if c = person.column("person_id"); c == nil || !c.notNull {
+		t.Errorf("person_id parsed incorrectly: %+v", c)
+	}
+	if c, err = parseColumnDefinition("active BOOLEAN DEFAULT true NOT NULL"); err != nil || c.sqlType != "BOOLEAN" || c.defaultValue != "true" || !c.notNull {
+		t.Errorf("Column with a default parsed incorrectly: %+v, %v", c, err)
+	}
+	if !reflect.DeepEqual(person.constraints, []string{"PRIMARY KEY (person_id)"}) {
+		t.Errorf("Unexpected table constraints: %v", person.constraints)
+	}
+}
+
+func TestUpgradeSql(t *testing.T) {
+	var (
+		from, to = new(modelDefinition), new(modelDefinition)
+		err      error
+	)
+	if from.tables, err = parseTableDefinitions(fixtureTables); err != nil {
+		t.Fatal(err)
+	}
+	if to.tables, err = parseTableDefinitions(upgradeToTables); err != nil {
+		t.Fatal(err)
+	}
+	if from.indexes, err = parseEntityDefinitions(upgradeFromIndexes, upgradeIndexPatterns); err != nil {
+		t.Fatal(err)
+	}
+	if to.indexes, err = parseEntityDefinitions(upgradeToIndexes, upgradeIndexPatterns); err != nil {
+		t.Fatal(err)
+	}
+	from.constraints = make(map[string]*entityDefinition)
+	if to.constraints, err = parseEntityDefinitions(upgradeToConstraints, upgradeConstraintPatterns); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"DROP INDEX idx_person_source",
+		"DROP INDEX idx_visit_payer_plan",
+		normalizeSql(upgradeToTables[1]),
+		"ALTER TABLE person DROP COLUMN pn_gestational_age",
+		"ALTER TABLE person ALTER COLUMN gender_concept_id DROP NOT NULL",
+		"ALTER TABLE person ALTER COLUMN year_of_birth SET DEFAULT 0",
+		"ALTER TABLE person ALTER COLUMN person_source_value TYPE VARCHAR(256)",
+		"ALTER TABLE person ADD COLUMN language_concept_id INTEGER",
+		"ALTER TABLE person ADD COLUMN race_concept_id INTEGER DEFAULT 0 NOT NULL",
+		"DROP TABLE visit_payer",
+		"CREATE INDEX idx_death_person ON death (person_id)",
+		"CREATE INDEX idx_person_source ON person (person_source_value, person_id)",
+		"ALTER TABLE death ADD CONSTRAINT fpk_death_person FOREIGN KEY(person_id) REFERENCES person (person_id)",
+	}
+
+	stmts := upgradeSql(from, to)
+	for i := range stmts {
+		stmts[i] = normalizeSql(stmts[i])
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("Unexpected upgrade SQL:\n%v\nexpected:\n%v", stmts, expected)
+	}
+}
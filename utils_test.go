@@ -5,12 +5,57 @@ package database
 import (
 	"archive/zip"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"testing"
+	"testing/fstest"
 )
 
+// fixtureTables is the table DDL of the small model that tests use in place of version 2.2.0 of the pedsnet model.
+var fixtureTables = []string{`
+CREATE TABLE person (
+	person_id INTEGER NOT NULL,
+	gender_concept_id INTEGER NOT NULL,
+	year_of_birth INTEGER NOT NULL,
+	person_source_value VARCHAR(50),
+	pn_gestational_age NUMERIC(4, 2),
+	PRIMARY KEY (person_id)
+)`, `
+CREATE TABLE visit_payer (
+	visit_payer_id INTEGER NOT NULL,
+	plan_name VARCHAR(255),
+	PRIMARY KEY (visit_payer_id)
+)`}
+
+// fixtureSource returns a DDLSource serving the table DDL `tables` as version 2.2.0 of the pedsnet model. Other DDL
+// may be added to its FS.
+func fixtureSource(tables []string) *FSSource {
+	return &FSSource{FS: fstest.MapFS{
+		"pedsnet/2.2.0/ddl/postgresql/tables.sql": {Data: []byte(strings.Join(tables, ";"))},
+	}}
+}
+
+// writeTestFiles writes the data `files`, keyed by name, to a temporary directory, removed when the test ends, and
+// returns the directory.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newFixtureDatabase returns a Database without a connection for the fixture model (see fixtureTables), and a
+// directory containing the data `files` (see writeTestFiles).
+func newFixtureDatabase(t *testing.T, files map[string]string) (*Database, string) {
+	return &Database{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: fixtureSource(fixtureTables)}, writeTestFiles(t, files)
+}
+
 // downloadFile creates a file and downloads a URL to it
 // http://stackoverflow.com/a/33853856/390663
 func downloadFile(filePath string, url string) (err error) {
@@ -0,0 +1,436 @@
+package database
+
+import (
+	"fmt"
+	"github.com/infomodels/datadirectory"
+	"io"
+	"math/big"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Maximum number of problems listed in a FileReport.
+const maxReportedProblems = 100
+
+// Bit sizes of integer types.
+var integerBits = map[string]int{"SMALLINT": 16, "INTEGER": 32, "INT": 32, "BIGINT": 64}
+
+// Month names, which PostgreSQL reads in dates in full or abbreviated to three letters or more.
+var monthNames = []string{"january", "february", "march", "april", "may", "june", "july", "august", "september", "october", "november", "december"}
+
+// Values PostgreSQL reads as dates and timestamps whatever the DateStyle.
+var specialDates = map[string]bool{"epoch": true, "infinity": true, "-infinity": true, "now": true, "today": true, "tomorrow": true, "yesterday": true}
+
+var (
+	numericPattern = regexp.MustCompile(`^[+-]?(\d*)(\.\d*)?([eE][+-]?\d+)?$`)
+	timePattern    = regexp.MustCompile(`^(\d{1,2}):(\d{2})(:(\d{2})(\.\d+)?)?(Z|[+-]\d{2}(:?\d{2})?)?$`)
+	zonePattern    = regexp.MustCompile(`^([+-]\d{1,2}(:?\d{2})?|[A-Za-z][A-Za-z/_]*)$`)
+	digitsPattern  = regexp.MustCompile(`^\d+$`)
+	sizePattern    = regexp.MustCompile(`\(\s*(\d+)\s*(,\s*(\d+)\s*)?\)`)
+	booleanValues  = map[string]bool{"t": true, "f": true, "true": true, "false": true, "y": true, "n": true, "yes": true, "no": true, "on": true, "off": true, "1": true, "0": true}
+)
+
+// size returns the size of the column's type, e.g. 50 for VARCHAR(50), or the precision and scale of a NUMERIC
+// type, or 0 if none is given.
+func (c *columnDefinition) size() (int, int) {
+	matches := sizePattern.FindStringSubmatch(c.sqlType)
+	if matches == nil {
+		return 0, 0
+	}
+	size, _ := strconv.Atoi(matches[1])
+	scale, _ := strconv.Atoi(matches[3])
+	return size, scale
+}
+
+// quoteValue quotes a value for a message, abbreviating a long value.
+func quoteValue(value string) string {
+	if utf8.RuneCountInString(value) > 40 {
+		value = string([]rune(value)[:37]) + "..."
+	}
+	return "'" + value + "'"
+}
+
+// valueProblem returns why the field `value` of data in `format` cannot be loaded into column `c`, or "" if it can.
+// `isNull` is whether the field stands for NULL. Values of types in no category (see columnDefinition.category) are
+// not checked.
+func valueProblem(c *columnDefinition, value string, isNull bool, format FileFormat) string {
+	if isNull {
+		if c.notNull {
+			return fmt.Sprintf("column `%s` is NOT NULL, but the value is NULL", c.name)
+		}
+		return ""
+	}
+
+	var ok bool
+	switch c.category() {
+	case "integer":
+		_, err := strconv.ParseInt(strings.TrimSpace(value), 10, integerBits[c.baseType()])
+		if numErr, isNumErr := err.(*strconv.NumError); isNumErr && numErr.Err == strconv.ErrRange {
+			return fmt.Sprintf("value %s of column `%s` is out of range for %s", quoteValue(value), c.name, c.sqlType)
+		}
+		ok = err == nil
+	case "numeric":
+		return numericProblem(c, strings.TrimSpace(value))
+	case "float":
+		_, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		ok = err == nil || isSpecialFloat(value)
+	case "date", "timestamp":
+		ok = isDate(strings.TrimSpace(value), format)
+	case "time":
+		ok = isTime(strings.TrimSpace(value))
+	case "boolean":
+		ok = booleanValues[strings.ToLower(strings.TrimSpace(value))]
+	case "text":
+		if size, _ := c.size(); size > 0 {
+			length := len(value)
+			if utf8.ValidString(value) {
+				length = utf8.RuneCountInString(value)
+			}
+			if length > size {
+				return fmt.Sprintf("value %s of column `%s` is longer than %d characters", quoteValue(value), c.name, size)
+			}
+		}
+		return ""
+	default:
+		return ""
+	}
+	if !ok {
+		return fmt.Sprintf("value %s of column `%s` is not a valid %s", quoteValue(value), c.name, c.sqlType)
+	}
+	return ""
+}
+
+// isSpecialFloat reports whether `value` is one of PostgreSQL's special floating point values.
+func isSpecialFloat(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "nan", "infinity", "+infinity", "-infinity", "inf", "+inf", "-inf":
+		return true
+	}
+	return false
+}
+
+// numericProblem returns why `value` cannot be loaded into NUMERIC column `c`, or "" if it can.
+func numericProblem(c *columnDefinition, value string) string {
+	if strings.ToLower(value) == "nan" {
+		return ""
+	}
+	matches := numericPattern.FindStringSubmatch(value)
+	if matches == nil || len(matches[1])+len(matches[2]) == 0 || matches[2] == "." && matches[1] == "" {
+		return fmt.Sprintf("value %s of column `%s` is not a valid %s", quoteValue(value), c.name, c.sqlType)
+	}
+
+	precision, scale := c.size()
+	if precision == 0 {
+		return ""
+	}
+	// Round to the scale, as PostgreSQL does, and count the digits before the decimal point.
+	r, ok := new(big.Rat).SetString(strings.TrimLeft(value, "+"))
+	if !ok {
+		return fmt.Sprintf("value %s of column `%s` is not a valid %s", quoteValue(value), c.name, c.sqlType)
+	}
+	integerPart := strings.TrimLeft(strings.SplitN(r.Abs(r).FloatString(scale), ".", 2)[0], "0")
+	if len(integerPart) > precision-scale {
+		return fmt.Sprintf("value %s of column `%s` has more than %d digits before the decimal point", quoteValue(value), c.name, precision-scale)
+	}
+	return ""
+}
+
+// isDate reports whether PostgreSQL reads `value` as a date, or a timestamp, in the DateStyle of the format (see
+// dateStyle), which decides whether a date whose year is not first has its month or its day first, e.g. "1/5/2020".
+// Dates have numeric fields separated by "-", "/", "." or spaces, with or without leading zeros, or a month name, e.g.
+// "5 Jan 2020", or are the digits of YYYYMMDD or YYMMDD. A date may be followed by a time of day (see isTime),
+// separated by a space or "T", with a time zone, and by "AD" or "BC".
+func isDate(value string, format FileFormat) bool {
+	if specialDates[strings.ToLower(value)] {
+		return true
+	}
+	var fields []string
+	hasTime := false
+	for _, token := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+		if i := strings.IndexAny(token, "Tt"); i > 0 && strings.Contains(token[i:], ":") {
+			fields = append(fields, dateFields(token[:i])...)
+			token = token[i+1:]
+		}
+		switch {
+		case strings.EqualFold(token, "AD") || strings.EqualFold(token, "BC"):
+		case strings.Contains(token, ":") && !hasTime:
+			if !isTime(token) {
+				return false
+			}
+			hasTime = true
+		case hasTime:
+			if !zonePattern.MatchString(token) {
+				return false
+			}
+		default:
+			fields = append(fields, dateFields(token)...)
+		}
+	}
+	return isDateFields(fields, format.dateStyle() == "ISO, DMY")
+}
+
+// dateFields splits the date `s` into its fields.
+func dateFields(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' || r == '.' })
+}
+
+// isDateFields reports whether PostgreSQL reads the fields of a date as a valid date, taking a date whose year is
+// not first to have its day first if `dayFirst`, and otherwise its month.
+func isDateFields(fields []string, dayFirst bool) bool {
+	var (
+		month   int
+		numbers []string
+	)
+	for _, field := range fields {
+		if digitsPattern.MatchString(field) {
+			numbers = append(numbers, field)
+			continue
+		}
+		if month != 0 {
+			return false
+		}
+		if month = monthNumber(field); month == 0 {
+			return false
+		}
+	}
+
+	switch {
+	case month != 0 && len(numbers) == 2:
+		// The year is the number with more than two digits, or else the last, e.g. "2020 Jan 5" or "Jan 5 20".
+		if len(numbers[0]) > 2 {
+			return isValidDate(numbers[0], strconv.Itoa(month), numbers[1])
+		}
+		return isValidDate(numbers[1], strconv.Itoa(month), numbers[0])
+	case month != 0:
+		return false
+	case len(numbers) == 1 && len(numbers[0]) == 8:
+		n := numbers[0]
+		return isValidDate(n[:4], n[4:6], n[6:])
+	case len(numbers) == 1 && len(numbers[0]) == 6:
+		n := numbers[0]
+		return isValidDate(n[:2], n[2:4], n[4:])
+	case len(numbers) == 2 && len(numbers[0]) == 4 && len(numbers[1]) == 3:
+		// Year and day of the year, e.g. "2020.005"
+		year, _ := strconv.Atoi(numbers[0])
+		day, _ := strconv.Atoi(numbers[1])
+		return year > 0 && day > 0 && day <= time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	case len(numbers) != 3:
+		return false
+	case len(numbers[0]) > 2:
+		return isValidDate(numbers[0], numbers[1], numbers[2])
+	case dayFirst:
+		return isValidDate(numbers[2], numbers[1], numbers[0])
+	}
+	return isValidDate(numbers[2], numbers[0], numbers[1])
+}
+
+// monthNumber returns the number of the month named `name`, or 0 if it is not a month name.
+func monthNumber(name string) int {
+	name = strings.ToLower(name)
+	if len(name) >= 3 {
+		for i, month := range monthNames {
+			if strings.HasPrefix(month, name) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// isValidDate reports whether the decimal `year`, `month` and `day` make a valid date. Years of one or two digits
+// are abbreviated, e.g. 20 for 2020.
+func isValidDate(year string, month string, day string) bool {
+	y, err := strconv.Atoi(year)
+	if err != nil || len(year) > 7 || (y == 0 && len(year) > 2) {
+		return false
+	}
+	if len(year) <= 2 {
+		y += 2000
+	}
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	if m < 1 || m > 12 || d < 1 {
+		return false
+	}
+	return d <= time.Date(y, time.Month(m)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// isTime reports whether `value` is a time of day, e.g. "08:30", "08:30:00.5" or "08:30:00+05:30".
+func isTime(value string) bool {
+	matches := timePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return false
+	}
+	hours, _ := strconv.Atoi(matches[1])
+	minutes, _ := strconv.Atoi(matches[2])
+	seconds, _ := strconv.Atoi(matches[4])
+	if hours == 24 {
+		return minutes == 0 && seconds == 0
+	}
+	return hours < 24 && minutes < 60 && seconds <= 60
+}
+
+// FileReport is the result of checking a data file against its table (see ValidateDataDirectory).
+type FileReport struct {
+	FileName    string
+	Table       string
+	Rows        int      // Number of data rows checked
+	InvalidRows int      // Number of rows that cannot be loaded
+	Problems    []string // The first problems found (see maxReportedProblems), each prefixed with its line number
+}
+
+// Valid reports whether every row of the file can be loaded.
+func (r *FileReport) Valid() bool {
+	return r.InvalidRows == 0 && len(r.Problems) == 0
+}
+
+func (r *FileReport) String() string {
+	s := fmt.Sprintf("%s (%s): %d rows, %d invalid", r.FileName, r.Table, r.Rows, r.InvalidRows)
+	for _, problem := range r.Problems {
+		s += "\n\t" + problem
+	}
+	if r.InvalidRows > len(r.Problems) {
+		s += fmt.Sprintf("\n\t(%d more invalid rows not listed)", r.InvalidRows-len(r.Problems))
+	}
+	return s
+}
+
+// ValidateDataDirectory checks the data files of `dataDirectory` against the column definitions of their tables,
+// without touching the database, so that a submission can be checked before it is sent. The manifest is validated
+// first (see ValidateManifest), and the problems of each entry are added to its file's report, whose values are then
+// not checked. Then every value of each file is checked as LoadOptions.Tolerant would check it: integers must be in
+// range, numbers must fit their precision and scale, dates and timestamps must be readable by PostgreSQL in the file's
+// DateFormat, strings must fit their VARCHAR lengths and NOT NULL columns must have values. Parquet and JSON Lines
+// files are checked as they are converted to be loaded, and their problems are numbered by row rather than line.
+//
+// A report is returned for each file of the manifest, in order; a file that cannot be read is reported as a problem of
+// its report rather than as an error. The Database need not be connected (see New).
+func (d *Database) ValidateDataDirectory(dataDirectory *datadirectory.DataDirectory) ([]*FileReport, error) {
+	tables, err := d.tableDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	formats, err := d.validateManifest(dataDirectory.RecordMaps, tables, directoryHeaders(dataDirectory.DirPath))
+	manifestErr, _ := err.(*ManifestError)
+	if err != nil && manifestErr == nil {
+		return nil, err
+	}
+
+	var reports []*FileReport
+	for i, m := range dataDirectory.RecordMaps {
+		report := &FileReport{FileName: m["filename"], Table: m["table"]}
+		reports = append(reports, report)
+		if manifestErr != nil && len(manifestErr.entryProblems[i]) > 0 {
+			for _, problem := range manifestErr.entryProblems[i] {
+				report.Problems = append(report.Problems, strings.TrimPrefix(problem, report.FileName+": "))
+			}
+			continue
+		}
+
+		fileName := path.Join(dataDirectory.DirPath, report.FileName)
+		converted := isConvertedFile(fileName)
+		var (
+			fileReader io.ReadCloser
+			format     = formats[i]
+		)
+		if converted {
+			fileReader, err = convertedData(fileName, tables[report.Table], format)
+			format = convertedFormat
+		} else {
+			fileReader, err = openDataFile(fileName)
+		}
+		if err != nil {
+			report.Problems = append(report.Problems, err.Error())
+			continue
+		}
+		validateRecords(report, fileReader, tables[report.Table], format, converted)
+		fileReader.Close()
+	}
+	return reports, nil
+}
+
+// convertedData returns the data of the Parquet or JSON Lines file `fileName`, in `format`, as it is loaded into table
+// `t`: CSV in convertedFormat (see copyParquet and copyJSONLines). An error converting the file is returned by a read.
+func convertedData(fileName string, t *tableDefinition, format FileFormat) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	if isJSONLinesFile(fileName) {
+		fileReader, err := openDataFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		var columns []string
+		for _, c := range t.columns {
+			columns = append(columns, c.name)
+		}
+		go func() {
+			_, err := writeJSONLinesCsv(w, fileReader, columns, format)
+			fileReader.Close()
+			w.CloseWithError(err)
+		}()
+		return r, nil
+	}
+
+	pf, f, err := openParquetFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	names, formatters, err := parquetColumns(pf, t)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	go func() {
+		err := writeParquetCsv(w, pf, names, formatters, format)
+		f.Close()
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
+
+// validateRecords checks the records of the data read from `r`, in `format`, against table `t`, adding the rows
+// checked and the problems found to `report`. Problems are numbered by row if `converted`, since the lines of
+// converted data are not those of its file.
+func validateRecords(report *FileReport, r io.Reader, t *tableDefinition, format FileFormat, converted bool) {
+	recordReader := format.newRecordReader(r)
+	columns := format.Columns
+	if !format.NoHeader {
+		header, err := recordReader.read(true)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("error reading the header: %v", err))
+			return
+		}
+		columns = format.headerColumns(header)
+	}
+	definitions := make([]*columnDefinition, len(columns))
+	for i, column := range columns {
+		definitions[i] = t.column(column)
+	}
+
+	for {
+		record, err := recordReader.read(true)
+		if err == io.EOF {
+			return
+		} else if err != nil && converted {
+			report.Problems = append(report.Problems, err.Error())
+			return
+		} else if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("line %d: %v", recordReader.line, err))
+			return
+		}
+		report.Rows++
+		if problem := recordProblem(record, columns, definitions, format); problem != "" {
+			report.InvalidRows++
+			if len(report.Problems) >= maxReportedProblems {
+				continue
+			} else if converted {
+				report.Problems = append(report.Problems, fmt.Sprintf("row %d: %s", report.Rows, problem))
+			} else {
+				report.Problems = append(report.Problems, fmt.Sprintf("line %d: %s", recordReader.line, problem))
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"github.com/infomodels/datadirectory"
+	"strings"
+	"testing"
+)
+
+const validateTable = `CREATE TABLE visit (
+	visit_id INTEGER NOT NULL,
+	visit_count SMALLINT,
+	visit_start_date DATE NOT NULL,
+	visit_start_datetime TIMESTAMP WITHOUT TIME ZONE,
+	visit_start_time VARCHAR(10),
+	charge NUMERIC(5, 2),
+	weight DOUBLE PRECISION,
+	note VARCHAR(5),
+	active BOOLEAN,
+	notes TEXT
+)`
+
+func TestValueProblem(t *testing.T) {
+	table, err := parseTableDefinition(validateTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		column  string
+		value   string
+		isNull  bool
+		format  FileFormat
+		problem string // Part of the expected problem, or "" for none
+	}{
+		{"visit_id", "42", false, FileFormat{}, ""},
+		{"visit_id", " -7 ", false, FileFormat{}, ""},
+		{"visit_id", "4.2", false, FileFormat{}, "is not a valid INTEGER"},
+		{"visit_id", "3000000000", false, FileFormat{}, "out of range"},
+		{"visit_id", "", true, FileFormat{}, "is NOT NULL"},
+		{"visit_count", "40000", false, FileFormat{}, "out of range"},
+		{"visit_count", "", true, FileFormat{}, ""},
+		{"visit_start_date", "2020-02-29", false, FileFormat{}, ""},
+		{"visit_start_date", "2019-02-29", false, FileFormat{}, "is not a valid DATE"},
+		{"visit_start_date", "2020-1-5", false, FileFormat{}, ""},
+		{"visit_start_date", "2020-01-05 00:00:00", false, FileFormat{}, ""},
+		{"visit_start_date", "5 Jan 2020", false, FileFormat{}, ""},
+		{"visit_start_date", "January 5, 2020 AD", false, FileFormat{}, ""},
+		{"visit_start_date", "2020.366", false, FileFormat{}, ""},
+		{"visit_start_date", "2020-13-05", false, FileFormat{}, "is not a valid DATE"},
+		{"visit_start_date", "5 Jen 2020", false, FileFormat{}, "is not a valid DATE"},
+		{"visit_start_date", "today", false, FileFormat{}, ""},
+		{"visit_start_date", "20200229", false, FileFormats["athena"], ""},
+		{"visit_start_date", "2020-02-29", false, FileFormats["athena"], ""},
+		{"visit_start_date", "20200230", false, FileFormats["athena"], "is not a valid DATE"},
+		{"visit_start_date", "12/31/2020", false, FileFormat{DateFormat: "MM/DD/YYYY"}, ""},
+		{"visit_start_date", "31/12/2020", false, FileFormat{DateFormat: "MM/DD/YYYY"}, "is not a valid DATE"},
+		{"visit_start_date", "31/12/20", false, FileFormat{DateFormat: "DD/MM/YYYY"}, ""},
+		{"visit_start_date", "1.5.2020", false, FileFormat{DateFormat: "DD/MM/YYYY"}, ""},
+		{"visit_start_date", "12/31/2020", false, FileFormat{DateFormat: "DD/MM/YYYY"}, "is not a valid DATE"},
+		{"visit_start_datetime", "2020-01-01 08:30:00.5", false, FileFormat{}, ""},
+		{"visit_start_datetime", "2020-01-01T08:30:00+05:30", false, FileFormat{}, ""},
+		{"visit_start_datetime", "2020-01-01 25:00", false, FileFormat{}, "is not a valid TIMESTAMP"},
+		{"visit_start_datetime", "2020-01-01 08:30 America/New_York", false, FileFormat{}, ""},
+		{"visit_start_datetime", "2020-01-01 08:30 2020", false, FileFormat{}, "is not a valid TIMESTAMP"},
+		{"charge", "123.45", false, FileFormat{}, ""},
+		{"charge", "999.999", false, FileFormat{}, "more than 3 digits"},
+		{"charge", "1000", false, FileFormat{}, "more than 3 digits"},
+		{"charge", "1e2", false, FileFormat{}, ""},
+		{"charge", "abc", false, FileFormat{}, "is not a valid NUMERIC(5, 2)"},
+		{"weight", "72.5", false, FileFormat{}, ""},
+		{"weight", "-Infinity", false, FileFormat{}, ""},
+		{"weight", "heavy", false, FileFormat{}, "is not a valid DOUBLE PRECISION"},
+		{"note", "héllo", false, FileFormat{}, ""},
+		{"note", "hello!", false, FileFormat{}, "longer than 5 characters"},
+		{"active", "Yes", false, FileFormat{}, ""},
+		{"active", "maybe", false, FileFormat{}, "is not a valid BOOLEAN"},
+		{"notes", strings.Repeat("x", 1000), false, FileFormat{}, ""},
+	}
+	for _, test := range tests {
+		c := table.column(test.column)
+		if c == nil {
+			t.Fatalf("No column %s", test.column)
+		}
+		problem := valueProblem(c, test.value, test.isNull, test.format)
+		if test.problem == "" && problem != "" || !strings.Contains(problem, test.problem) {
+			t.Errorf("Value %q of %s: got problem %q; expected %q", test.value, test.column, problem, test.problem)
+		}
+	}
+}
+
+func TestValidateDataDirectory(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"visit.csv": "visit_id,visit_start_date,charge,note\n" +
+			"1,2020-01-01,12.5,ok\n" +
+			"two,2020-01-01,,\n" +
+			"3,,1234.5,too long\n" +
+			"4,2020-01-02,,\"a\nb\"\n",
+		"care_site.tsv": "1\tMain\n2\tSatellite clinic\n",
+		"care_site.jsonl": `{"care_site_id": 3, "care_site_name": "East"}` + "\n\n" +
+			`{"care_site_id": 4, "care_site_name": "Satellite clinic"}` + "\n",
+	})
+
+	// No database is needed.
+	source := fixtureSource([]string{validateTable, "CREATE TABLE care_site (\n\tcare_site_id INTEGER NOT NULL,\n\tcare_site_name VARCHAR(10)\n)"})
+	d, err := New(&Options{Model: "pedsnet", ModelVersion: "2.2.0", DDLSource: source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataDirectory := &datadirectory.DataDirectory{DirPath: dir, RecordMaps: []map[string]string{
+		{"table": "visit", "filename": "visit.csv"},
+		{"table": "care_site", "filename": "care_site.tsv", "format": "tsv", "header": "false"},
+		{"table": "care_site", "filename": "care_site.jsonl"},
+	}}
+	reports, err := d.ValidateDataDirectory(dataDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Expected 3 reports, got %d", len(reports))
+	}
+
+	visit := reports[0]
+	if visit.Valid() || visit.FileName != "visit.csv" || visit.Table != "visit" || visit.Rows != 4 || visit.InvalidRows != 2 || len(visit.Problems) != 2 {
+		t.Fatalf("Unexpected report %s", visit)
+	}
+	if !strings.HasPrefix(visit.Problems[0], "line 3: value 'two' of column `visit_id`") {
+		t.Errorf("Unexpected problem %s", visit.Problems[0])
+	}
+	for _, expected := range []string{"line 4: ", "`visit_start_date` is NOT NULL", "more than 3 digits", "longer than 5 characters"} {
+		if !strings.Contains(visit.Problems[1], expected) {
+			t.Errorf("Expected problem %s to contain %s", visit.Problems[1], expected)
+		}
+	}
+
+	careSite := reports[1]
+	if careSite.Valid() || careSite.Rows != 2 || careSite.InvalidRows != 1 || !strings.HasPrefix(careSite.Problems[0], "line 2: value 'Satellite clinic'") {
+		t.Errorf("Unexpected report %s", careSite)
+	}
+
+	// The second file of care_site is a manifest problem, so its values are not checked.
+	careSiteJSON := reports[2]
+	if careSiteJSON.Valid() || careSiteJSON.Rows != 0 || len(careSiteJSON.Problems) != 1 || careSiteJSON.Problems[0] != "table `care_site` is also loaded from care_site.tsv" {
+		t.Errorf("Unexpected report %s", careSiteJSON)
+	}
+
+	dataDirectory.RecordMaps = []map[string]string{
+		{"table": "visit", "filename": "missing.csv"},
+		{"table": "care_site", "filename": "care_site.jsonl"},
+	}
+	if reports, err = d.ValidateDataDirectory(dataDirectory); err != nil {
+		t.Fatal(err)
+	}
+	if missing := reports[0]; missing.Valid() || len(missing.Problems) != 1 || missing.Problems[0] != "file does not exist" {
+		t.Errorf("Unexpected report %s", missing)
+	}
+	if careSiteJSON = reports[1]; careSiteJSON.Valid() || careSiteJSON.Rows != 2 || careSiteJSON.InvalidRows != 1 || !strings.HasPrefix(careSiteJSON.Problems[0], "row 2: value 'Satellite clinic'") {
+		t.Errorf("Unexpected report %s", careSiteJSON)
+	}
+}
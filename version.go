@@ -0,0 +1,209 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ModelVersion is a parsed data model version such as 2.2.0. Versions may be given with fewer than three
+// parts (e.g. "2.2"), in which case the missing parts are zero.
+type ModelVersion struct {
+	Major, Minor, Patch int
+
+	parts int // Number of parts given when parsed
+}
+
+// ParseModelVersion parses a version of the form X, X.Y or X.Y.Z, optionally prefixed with "v".
+func ParseModelVersion(version string) (ModelVersion, error) {
+	var v ModelVersion
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("Version string must be like X, X.Y or X.Y.Z, not '%s'", version)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("Version string must be like X, X.Y or X.Y.Z, not '%s'", version)
+		}
+		*numbers[i] = n
+	}
+	v.parts = len(parts)
+	return v, nil
+}
+
+// String returns the version as X.Y.Z.
+func (v ModelVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Complete returns true if the version was given with all three parts, X.Y.Z.
+func (v ModelVersion) Complete() bool {
+	return v.parts == 3
+}
+
+// Shorthand returns the major and minor version run together, e.g. "22" for 2.2.0.
+// TODO: this is an unscalable convention, obviously
+func (v ModelVersion) Shorthand() string {
+	return fmt.Sprintf("%d%d", v.Major, v.Minor)
+}
+
+// Compare returns -1, 0 or 1 as `v` is older than, the same as, or newer than `other`.
+func (v ModelVersion) Compare(other ModelVersion) int {
+	a := []int{v.Major, v.Minor, v.Patch}
+	b := []int{other.Major, other.Minor, other.Patch}
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Less returns true if `v` is older than `other`.
+func (v ModelVersion) Less(other ModelVersion) bool {
+	return v.Compare(other) < 0
+}
+
+// versionComparison is a single comparison within a VersionRange, e.g. ">= 2.1.0".
+type versionComparison struct {
+	operator string // One of "=", "!=", "<", "<=", ">", ">="
+	version  ModelVersion
+}
+
+func (c versionComparison) matches(v ModelVersion) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+// VersionRange is a set of model versions, parsed by ParseVersionRange.
+type VersionRange struct {
+	alternatives [][]versionComparison // Versions matching all comparisons of any alternative are in the range
+	text         string
+}
+
+// ParseVersionRange parses a version range. A range is one or more space-separated terms, all of which must match;
+// alternative ranges may be separated by "||". A term is one of:
+//
+//	X.Y.Z       exactly X.Y.Z (also "=X.Y.Z")
+//	X.Y         the X.Y series, i.e. ~X.Y
+//	X           the X series, i.e. ^X
+//	~X.Y[.Z]    at least X.Y[.Z] and before X.(Y+1)
+//	^X[.Y[.Z]]  at least X[.Y[.Z]] and before (X+1)
+//	<V, <=V, >V, >=V, !=V
+//	*           any version
+//
+// An empty range contains every version.
+func ParseVersionRange(text string) (*VersionRange, error) {
+	r := &VersionRange{text: text}
+	for _, alternative := range strings.Split(text, "||") {
+		var comparisons []versionComparison
+		for _, term := range strings.Fields(alternative) {
+			terms, err := parseVersionTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid version range '%s': %v", text, err)
+			}
+			comparisons = append(comparisons, terms...)
+		}
+		r.alternatives = append(r.alternatives, comparisons)
+	}
+	return r, nil
+}
+
+// parseVersionTerm parses a single term of a version range into comparisons.
+func parseVersionTerm(term string) ([]versionComparison, error) {
+	if term == "*" {
+		return nil, nil
+	}
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(term, op) {
+			v, err := ParseModelVersion(term[len(op):])
+			if err != nil {
+				return nil, err
+			}
+			return []versionComparison{{op, v}}, nil
+		}
+	}
+
+	prefix := ""
+	if strings.HasPrefix(term, "~") || strings.HasPrefix(term, "^") {
+		prefix, term = term[:1], term[1:]
+	}
+	v, err := ParseModelVersion(term)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		switch v.parts {
+		case 3:
+			return []versionComparison{{"=", v}}, nil
+		case 2:
+			prefix = "~"
+		default:
+			prefix = "^"
+		}
+	}
+
+	var upper ModelVersion
+	if prefix == "~" && v.parts > 1 {
+		upper = ModelVersion{Major: v.Major, Minor: v.Minor + 1}
+	} else {
+		upper = ModelVersion{Major: v.Major + 1}
+	}
+	return []versionComparison{{">=", v}, {"<", upper}}, nil
+}
+
+// Contains returns true if `v` is in the range.
+func (r *VersionRange) Contains(v ModelVersion) bool {
+	for _, comparisons := range r.alternatives {
+		matches := true
+		for _, c := range comparisons {
+			if !c.matches(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the range as it was given to ParseVersionRange.
+func (r *VersionRange) String() string {
+	return r.text
+}
+
+// versionInRange returns true if the version string `version` is in the range `versionRange` (see ParseVersionRange).
+// Unparseable versions or ranges match only if the strings are identical.
+func versionInRange(version string, versionRange string) bool {
+	if version == versionRange {
+		return true
+	}
+	v, err := ParseModelVersion(version)
+	if err != nil {
+		return false
+	}
+	r, err := ParseVersionRange(versionRange)
+	if err != nil {
+		return false
+	}
+	return r.Contains(v)
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"time"
+)
+
+// Version is the version of this package.
+const Version = "0.1.0"
+
+// toolVersion identifies this package, and its version, in the records it writes.
+var toolVersion = "infomodels/database " + Version
+
+// VersionHistoryEntry is a row of the `version_history` table maintained by data-models-sqlalchemy DDL and by this package.
+type VersionHistoryEntry struct {
+	Operation    string    // E.g. "create tables", "load" or "upgrade from 2.2.0"
+	Model        string    // Model per https://github.com/chop-dbhi/data-models.
+	ModelVersion string    // Model version per https://github.com/chop-dbhi/data-models.
+	DmsVersion   string    // data-models-service version, or "" if not recorded.
+	DmsaVersion  string    // data-models-sqlalchemy version, or "" if not recorded, as for operations recorded by this package.
+	Datetime     time.Time // When the operation was performed.
+}
+
+// hasVersionHistory returns true if a `version_history` table is visible in the search path.
+func (d *Database) hasVersionHistory() (bool, error) {
+	db, err := d.connection()
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	if err = db.QueryRow("SELECT to_regclass('version_history') IS NOT NULL").Scan(&exists); err != nil {
+		return false, fmt.Errorf("Error checking for version_history table: %v", err)
+	}
+	return exists, nil
+}
+
+// RecordOperation adds a row for `operation` on the data model to the `version_history` table, using the Database's
+// Executor like its DDL operations. The `dms_version` and `dmsa_version` columns, which record the versions of the
+// services that produced DDL, are left NULL.
+func (d *Database) RecordOperation(operation string) error {
+	sql := "INSERT INTO version_history (operation, model, model_version, datetime) VALUES ($1, $2, $3, now())"
+	if err := executeSQLArgs(d.executor(), sql, operation, d.Model, d.ModelVersion); err != nil {
+		return fmt.Errorf("Error recording `%s` in version_history: %v", operation, err)
+	}
+	return nil
+}
+
+// recordOperation records `operation` (see RecordOperation) after a DDL operation run in error sensitivity level
+// `errorMode` (see executeStatements), which applies to a failure to record it as to the operation's statements.
+func (d *Database) recordOperation(operation string, errorMode string) error {
+	err := d.RecordOperation(operation)
+	if err == nil {
+		return nil
+	}
+	fatal, modeErr := isFatalError(err, errorMode)
+	if modeErr != nil {
+		return modeErr
+	} else if fatal {
+		return err
+	}
+	log.Warn(fmt.Sprintf("Ignoring error in %s mode: %v", errorMode, err))
+	return nil
+}
+
+// VersionHistory returns the rows of the `version_history` table for the data model, oldest first.
+// If there is no `version_history` table, no entries are returned.
+func (d *Database) VersionHistory() ([]*VersionHistoryEntry, error) {
+	exists, err := d.hasVersionHistory()
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	query := "SELECT operation, model, model_version, dms_version, dmsa_version, datetime FROM version_history WHERE model = $1 ORDER BY datetime"
+	rows, err := d.db.Query(query, d.Model)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying version_history: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*VersionHistoryEntry
+	for rows.Next() {
+		var (
+			e                       = new(VersionHistoryEntry)
+			dmsVersion, dmsaVersion sql.NullString
+		)
+		if err = rows.Scan(&e.Operation, &e.Model, &e.ModelVersion, &dmsVersion, &dmsaVersion, &e.Datetime); err != nil {
+			return nil, fmt.Errorf("Error reading version_history: %v", err)
+		}
+		e.DmsVersion = dmsVersion.String
+		e.DmsaVersion = dmsaVersion.String
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading version_history: %v", err)
+	}
+	return entries, nil
+}
+
+// InstalledModelVersion returns the model version most recently recorded in the `version_history` table for the data model,
+// or "" if none is recorded.
+func (d *Database) InstalledModelVersion() (string, error) {
+	entries, err := d.VersionHistory()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return entries[len(entries)-1].ModelVersion, nil
+}
+
+// checkInstalledModelVersion compares the installed model version with `d.ModelVersion`.
+// `mode` is "warn" (or "") to log a warning on disagreement, "refuse" to return an error, or "ignore" to skip the check.
+func (d *Database) checkInstalledModelVersion(mode string) error {
+	if mode == "ignore" {
+		return nil
+	}
+
+	installed, err := d.InstalledModelVersion()
+	if err != nil {
+		return err
+	}
+	if installed == "" || installed == d.ModelVersion {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Version %s of model %s was requested, but version %s is installed", d.ModelVersion, d.Model, installed)
+	if mode == "refuse" {
+		return fmt.Errorf("%s", msg)
+	}
+	log.WithFields(log.Fields{"Installed": installed, "Requested": d.ModelVersion}).Warn(msg)
+	return nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestParseModelVersion(t *testing.T) {
+	for _, c := range []struct {
+		version  string
+		expected string
+		complete bool
+	}{
+		{"2.2.0", "2.2.0", true},
+		{"v2.10.1", "2.10.1", true},
+		{"2.2", "2.2.0", false},
+		{"2", "2.0.0", false},
+	} {
+		v, err := ParseModelVersion(c.version)
+		if err != nil {
+			t.Errorf("Cannot parse %s: %v", c.version, err)
+			continue
+		}
+		if v.String() != c.expected || v.Complete() != c.complete {
+			t.Errorf("Parsed %s as %s (complete %v), expected %s (complete %v)", c.version, v, v.Complete(), c.expected, c.complete)
+		}
+	}
+	for _, version := range []string{"", "x.y", "2.2.0.1", "2.-1"} {
+		if _, err := ParseModelVersion(version); err == nil {
+			t.Errorf("ParseModelVersion accepted '%s'", version)
+		}
+	}
+
+	a, _ := ParseModelVersion("2.9.0")
+	b, _ := ParseModelVersion("2.10.0")
+	if !a.Less(b) || b.Less(a) || a.Compare(a) != 0 {
+		t.Error("Versions must be compared numerically")
+	}
+}
+
+func TestParseVersionRange(t *testing.T) {
+	for _, c := range []struct {
+		versionRange string
+		in           []string
+		out          []string
+	}{
+		{"2.2.0", []string{"2.2.0"}, []string{"2.2.1"}},
+		{"2.2", []string{"2.2.0", "2.2.7"}, []string{"2.1.9", "2.3.0"}},
+		{"~2.2.1", []string{"2.2.1", "2.2.5"}, []string{"2.2.0", "2.3.0"}},
+		{"2", []string{"2.0.0", "2.9.9"}, []string{"1.9.0", "3.0.0"}},
+		{"^2.1", []string{"2.1.0", "2.5.0"}, []string{"2.0.9", "3.0.0"}},
+		{">=2.1 <3", []string{"2.1.0", "2.9.0"}, []string{"2.0.0", "3.0.0"}},
+		{"2.0 || >=2.2.1", []string{"2.0.3", "2.2.1", "4.0.0"}, []string{"2.1.0", "2.2.0"}},
+		{"!=2.2.0", []string{"2.2.1"}, []string{"2.2.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+		{"", []string{"2.2.0"}, nil},
+	} {
+		r, err := ParseVersionRange(c.versionRange)
+		if err != nil {
+			t.Errorf("Cannot parse range '%s': %v", c.versionRange, err)
+			continue
+		}
+		for _, version := range c.in {
+			if v, _ := ParseModelVersion(version); !r.Contains(v) {
+				t.Errorf("Range '%s' should contain %s", c.versionRange, version)
+			}
+		}
+		for _, version := range c.out {
+			if v, _ := ParseModelVersion(version); r.Contains(v) {
+				t.Errorf("Range '%s' should not contain %s", c.versionRange, version)
+			}
+		}
+	}
+	for _, versionRange := range []string{"x.y", ">=", "~2.2 <x"} {
+		if _, err := ParseVersionRange(versionRange); err == nil {
+			t.Errorf("ParseVersionRange accepted '%s'", versionRange)
+		}
+	}
+}